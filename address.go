@@ -0,0 +1,182 @@
+//
+// address.go
+// Copyright (C) 2017 weirdgiraffe <giraffe@cyberzoo.xyz>
+//
+// Distributed under terms of the MIT license.
+//
+
+package bitcoin
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// BadAddress is returned when address string is malformed or is
+	// not valid for the network
+	BadAddress = errors.New("Bad address")
+	// BadChecksum is returned when address is well formed, but its
+	// checksum does not match, which usually means a typo
+	BadChecksum = errors.New("Bad address checksum")
+	// NoAddress is returned for output scripts which have no address
+	NoAddress = errors.New("Script has no address")
+)
+
+// Network holds address prefixes of bitcoin network
+type Network struct {
+	Name             string
+	PubKeyHashAddrID byte
+	ScriptHashAddrID byte
+	Bech32HRP        string
+}
+
+var (
+	MainNet = &Network{Name: "main", PubKeyHashAddrID: 0x00, ScriptHashAddrID: 0x05, Bech32HRP: "bc"}
+	TestNet = &Network{Name: "test", PubKeyHashAddrID: 0x6f, ScriptHashAddrID: 0xc4, Bech32HRP: "tb"}
+	SigNet  = &Network{Name: "signet", PubKeyHashAddrID: 0x6f, ScriptHashAddrID: 0xc4, Bech32HRP: "tb"}
+	RegTest = &Network{Name: "regtest", PubKeyHashAddrID: 0x6f, ScriptHashAddrID: 0xc4, Bech32HRP: "bcrt"}
+)
+
+// Address is a human readable form of standard output script
+type Address interface {
+	// String returns encoded address
+	String() string
+	// Script returns output script paying to the address
+	Script() []byte
+	// Class returns the class of output script
+	Class() ScriptClass
+}
+
+// PubKeyHashAddress is an address of pay-to-pubkey-hash output
+type PubKeyHashAddress struct {
+	Net  *Network
+	Hash []byte
+}
+
+func (a *PubKeyHashAddress) String() string {
+	return Base58CheckEncode(a.Net.PubKeyHashAddrID, a.Hash)
+}
+
+func (a *PubKeyHashAddress) Script() []byte {
+	ret := []byte{OP_DUP, OP_HASH160}
+	ret = append(ret, pushData(a.Hash)...)
+	return append(ret, OP_EQUALVERIFY, OP_CHECKSIG)
+}
+
+func (a *PubKeyHashAddress) Class() ScriptClass { return PubKeyHashScript }
+
+// ScriptHashAddress is an address of pay-to-script-hash output
+type ScriptHashAddress struct {
+	Net  *Network
+	Hash []byte
+}
+
+func (a *ScriptHashAddress) String() string {
+	return Base58CheckEncode(a.Net.ScriptHashAddrID, a.Hash)
+}
+
+func (a *ScriptHashAddress) Script() []byte {
+	ret := []byte{OP_HASH160}
+	ret = append(ret, pushData(a.Hash)...)
+	return append(ret, OP_EQUAL)
+}
+
+func (a *ScriptHashAddress) Class() ScriptClass { return ScriptHashScript }
+
+// WitnessAddress is an address of witness program output. Version 0
+// and 1 programs of the standard size are WitnessV0KeyHashScript,
+// WitnessV0ScriptHashScript and WitnessV1TaprootScript, the rest are
+// WitnessUnknownScript
+type WitnessAddress struct {
+	Net     *Network
+	Version int
+	Program []byte
+}
+
+func (a *WitnessAddress) String() string {
+	s, err := EncodeSegWitAddress(a.Net.Bech32HRP, a.Version, a.Program)
+	if err != nil {
+		return "<invalid witness address: " + err.Error() + ">"
+	}
+	return s
+}
+
+func (a *WitnessAddress) Script() []byte {
+	op := byte(OP_0)
+	if a.Version > 0 {
+		op = byte(OP_1 + a.Version - 1)
+	}
+	return append([]byte{op}, pushData(a.Program)...)
+}
+
+func (a *WitnessAddress) Class() ScriptClass {
+	switch {
+	case a.Version == 0 && len(a.Program) == 20:
+		return WitnessV0KeyHashScript
+	case a.Version == 0 && len(a.Program) == 32:
+		return WitnessV0ScriptHashScript
+	case a.Version == 1 && len(a.Program) == 32:
+		return WitnessV1TaprootScript
+	}
+	return WitnessUnknownScript
+}
+
+// ScriptToAddress returns address of output script for the network.
+// NoAddress is returned for pay-to-pubkey, multisig, null data and non
+// standard scripts, the same as in bitcoin core. Pay-to-pubkey script
+// has no address of its own
+func ScriptToAddress(script []byte, net *Network) (Address, error) {
+	info := ClassifyScript(script)
+	switch info.Class {
+	case PubKeyHashScript:
+		return &PubKeyHashAddress{Net: net, Hash: info.Hash}, nil
+	case ScriptHashScript:
+		return &ScriptHashAddress{Net: net, Hash: info.Hash}, nil
+	case WitnessV0KeyHashScript, WitnessV0ScriptHashScript,
+		WitnessV1TaprootScript, WitnessUnknownScript:
+		return &WitnessAddress{
+			Net:     net,
+			Version: info.WitnessVersion,
+			Program: info.WitnessProgram,
+		}, nil
+	}
+	return nil, fmt.Errorf("%s script: %w", info.Class, NoAddress)
+}
+
+// DecodeAddress parses address of the network. Errors wrap BadChecksum
+// when address checksum does not match and BadAddress otherwise
+func DecodeAddress(addr string, net *Network) (Address, error) {
+	if strings.HasPrefix(strings.ToLower(addr), net.Bech32HRP+"1") {
+		version, program, err := DecodeSegWitAddress(net.Bech32HRP, addr)
+		if err != nil {
+			return nil, err
+		}
+		return &WitnessAddress{Net: net, Version: version, Program: program}, nil
+	}
+	version, payload, err := Base58CheckDecode(addr)
+	if err != nil {
+		return nil, err
+	}
+	if len(payload) != 20 {
+		return nil, fmt.Errorf("invalid payload length %d: %w", len(payload), BadAddress)
+	}
+	switch version {
+	case net.PubKeyHashAddrID:
+		return &PubKeyHashAddress{Net: net, Hash: payload}, nil
+	case net.ScriptHashAddrID:
+		return &ScriptHashAddress{Net: net, Hash: payload}, nil
+	}
+	return nil, fmt.Errorf("version 0x%02x is not valid for %s network: %w", version, net.Name, BadAddress)
+}
+
+// AddressToScript returns output script paying to address of the
+// network
+func AddressToScript(addr string, net *Network) ([]byte, error) {
+	a, err := DecodeAddress(addr, net)
+	if err != nil {
+		return nil, err
+	}
+	return a.Script(), nil
+}
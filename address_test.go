@@ -0,0 +1,151 @@
+//
+// address_test.go
+// Copyright (C) 2017 weirdgiraffe <giraffe@cyberzoo.xyz>
+//
+// Distributed under terms of the MIT license.
+//
+
+package bitcoin
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// base58 test vectors from bitcoin core
+func TestBase58(t *testing.T) {
+	tt := []struct {
+		hex     string
+		encoded string
+	}{
+		{"", ""},
+		{"61", "2g"},
+		{"626262", "a3gV"},
+		{"636363", "aPEr"},
+		{"73696d706c792061206c6f6e6720737472696e67", "2cFupjhnEsSn59qHXstmK2ffpLv2"},
+		{"00eb15231dfceb60925886b67d065299925915aeb172c06647", "1NS17iag9jJgTHD1VXjvLCEnZuQ3rJDE9L"},
+		{"516b6fcd0f", "ABnLTmg"},
+		{"bf4f89001e670274dd", "3SEo3LWLoPntC"},
+		{"572e4794", "3EFU7m"},
+		{"ecac89cad93923c02321", "EJDM8drfXA6uyA"},
+		{"10c8511e", "Rt5zm"},
+		{"00000000000000000000", "1111111111"},
+	}
+	for i := range tt {
+		s := Base58Encode(hex2byte(tt[i].hex))
+		if s != tt[i].encoded {
+			t.Errorf("case #%d: expected %s, got %s", i, tt[i].encoded, s)
+		}
+		b, err := Base58Decode(tt[i].encoded)
+		if err != nil {
+			t.Errorf("case #%d: unexpected error: %v", i, err)
+			continue
+		}
+		if !bytes.Equal(b, hex2byte(tt[i].hex)) {
+			t.Errorf("case #%d: expected %s, got %x", i, tt[i].hex, b)
+		}
+	}
+	if _, err := Base58Decode("3SEo3LWLoPn0C"); !errors.Is(err, BadAddress) {
+		t.Errorf("expected BadAddress for invalid character, got %v", err)
+	}
+}
+
+func TestDecodeAddress(t *testing.T) {
+	tt := []struct {
+		addr       string
+		net        *Network
+		class      ScriptClass
+		script     string
+		expect_err error
+	}{
+		{"1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa", MainNet, PubKeyHashScript, "76a91462e907b15cbf27d5425399ebf6f0fb50ebb88f1888ac", nil},
+		{"1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNb", MainNet, 0, "", BadChecksum},
+		{"1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa", TestNet, 0, "", BadAddress},
+		{"1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfN", MainNet, 0, "", BadChecksum},
+		{"1A1zP1eP5QGefi2DMPTfTL5SLmv7Divf0a", MainNet, 0, "", BadAddress},
+		{"3J98t1WpEZ73CNmQviecrnyiWrnqRhWNLy", MainNet, ScriptHashScript, "a914b472a266d0bd89c13706a4132ccfb16f7c3b9fcb87", nil},
+		{"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4", MainNet, WitnessV0KeyHashScript, "0014751e76e8199196d454941c45d1b3a323f1433bd6", nil},
+		{"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4", TestNet, 0, "", BadAddress},
+		{"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t5", MainNet, 0, "", BadChecksum},
+		{"tb1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3q0sl5k7", SigNet, WitnessV0ScriptHashScript, "00201863143c14c5166804bd19203356da136c985678cd4d27a1b8c6329604903262", nil},
+		{"bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0", MainNet, WitnessV1TaprootScript, "512079be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798", nil},
+		{"bc1zw508d6qejxtdg4y5r3zarvaryvaxxpcs", MainNet, WitnessUnknownScript, "5210751e76e8199196d454941c45d1b3a323", nil},
+	}
+	for i := range tt {
+		a, err := DecodeAddress(tt[i].addr, tt[i].net)
+		if tt[i].expect_err != nil {
+			if !errors.Is(err, tt[i].expect_err) {
+				t.Errorf("case #%d: expected error %v, got %v", i, tt[i].expect_err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("case #%d: unexpected error: %v", i, err)
+			continue
+		}
+		if a.Class() != tt[i].class {
+			t.Errorf("case #%d: expected class %s, got %s", i, tt[i].class, a.Class())
+		}
+		if !bytes.Equal(a.Script(), hex2byte(tt[i].script)) {
+			t.Errorf("case #%d: expected script %s, got %x", i, tt[i].script, a.Script())
+		}
+		if a.String() != tt[i].addr {
+			t.Errorf("case #%d: expected address %s, got %s", i, tt[i].addr, a.String())
+		}
+		b, err := ScriptToAddress(a.Script(), tt[i].net)
+		if err != nil {
+			t.Errorf("case #%d: unexpected script error: %v", i, err)
+			continue
+		}
+		if b.String() != tt[i].addr {
+			t.Errorf("case #%d: expected script address %s, got %s", i, tt[i].addr, b.String())
+		}
+	}
+}
+
+func TestScriptToAddress(t *testing.T) {
+	// genesis block coinbase output
+	genesisPubKey := "04678afdb0fe5548271967f1a67130b7105cd6a828e03909a67962e0ea1f61deb649f6bc3f4cef38c4f35504e51ec112de5c384df7ba0b8d578a4c702b6bf11d5f"
+	h20 := "62e907b15cbf27d5425399ebf6f0fb50ebb88f18"
+	tt := []struct {
+		script     string
+		net        *Network
+		addr       string
+		expect_err error
+	}{
+		{"41" + genesisPubKey + "ac", MainNet, "", NoAddress},
+		{"76a914" + h20 + "88ac", TestNet, "mpXwg4jMtRhuSpVq4xS3HFHmCmWp9NyGKt", nil},
+		{"0014" + h20, RegTest, "bcrt1qvt5s0v2uhuna2sjnn84ldu8m2r4m3rccaqhe07", nil},
+		{"6a0401020304", MainNet, "", NoAddress},
+		{"5121" + "038479a0fa998cd35259a2ef0a7a5c68662c1474f88ccb6d08a7677bbec7f22041" + "51ae", MainNet, "", NoAddress},
+		{"", MainNet, "", NoAddress},
+	}
+	for i := range tt {
+		a, err := ScriptToAddress(hex2byte(tt[i].script), tt[i].net)
+		if tt[i].expect_err != nil {
+			if !errors.Is(err, tt[i].expect_err) {
+				t.Errorf("case #%d: expected error %v, got %v", i, tt[i].expect_err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("case #%d: unexpected error: %v", i, err)
+			continue
+		}
+		if a.String() != tt[i].addr {
+			t.Errorf("case #%d: expected address %s, got %s", i, tt[i].addr, a.String())
+		}
+		if !bytes.Equal(a.Script(), hex2byte(tt[i].script)) {
+			t.Errorf("case #%d: expected address script %s, got %x", i, tt[i].script, a.Script())
+		}
+		script, err := AddressToScript(a.String(), tt[i].net)
+		if err != nil {
+			t.Errorf("case #%d: unexpected error: %v", i, err)
+			continue
+		}
+		if !bytes.Equal(script, hex2byte(tt[i].script)) {
+			t.Errorf("case #%d: expected script %s, got %x", i, tt[i].script, script)
+		}
+	}
+}
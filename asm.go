@@ -0,0 +1,149 @@
+//
+// asm.go
+// Copyright (C) 2017 weirdgiraffe <giraffe@cyberzoo.xyz>
+//
+// Distributed under terms of the MIT license.
+//
+
+package bitcoin
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// AsmError describes why script text could not be assembled
+type AsmError struct {
+	// Pos is the byte offset of the bad token in the text
+	Pos   int
+	Token string
+	Msg   string
+}
+
+func (e *AsmError) Error() string {
+	return fmt.Sprintf("asm: position %d: %s: %q", e.Pos, e.Msg, e.Token)
+}
+
+// Assemble converts human readable script into bytes. It accepts the
+// output of Disassemble as well as the notation of bitcoin core test
+// vectors, tokens are separated by whitespace:
+//
+//   - operation names with or without OP_ prefix: OP_DUP, CHECKSIG
+//   - decimal numbers in range of -0xffffffff...0xffffffff, which are
+//     pushed as script numbers: 0, -1, 1000
+//   - hex data, which is pushed: 5b6462475454710f3c22f5fdf0b40704c92f25c3
+//   - hex signature with sighash suffix, which is pushed: 3006...[ALL]
+//   - 0x prefixed hex, which is inserted into the script as is: 0x14
+//   - quoted strings, which are pushed: 'Hello'
+//
+// data is pushed with the smallest possible push operation. Decimal
+// numbers take precedence over hex data, so data which looks like a
+// number in the range must be written with 0x prefix and explicit push
+// operation. Hex data of Disassemble output which consists of digits is
+// read back as hex only if it is out of the range, i.e. 9999999999
+// is the push of 5 bytes, but 1234567890 is the number
+func Assemble(asm string) ([]byte, error) {
+	var script []byte
+	for pos := 0; pos < len(asm); {
+		if unicode.IsSpace(rune(asm[pos])) {
+			pos++
+			continue
+		}
+		end := pos
+		for end < len(asm) && !unicode.IsSpace(rune(asm[end])) {
+			end++
+		}
+		b, err := assembleToken(asm[pos:end])
+		if err != nil {
+			return nil, &AsmError{Pos: pos, Token: asm[pos:end], Msg: err.Error()}
+		}
+		script = append(script, b...)
+		pos = end
+	}
+	return script, nil
+}
+
+func assembleToken(w string) ([]byte, error) {
+	if isDecimal(w) && !isDecimalHex(w) {
+		// the same range as bitcoin core accepts, numbers outside of
+		// it are illegal in scripts
+		v, err := strconv.ParseInt(w, 10, 64)
+		if err != nil || v > 0xffffffff || v < -0xffffffff {
+			return nil, fmt.Errorf("number out of range")
+		}
+		switch {
+		case v == 0:
+			return []byte{OP_0}, nil
+		case v == -1:
+			return []byte{OP_1NEGATE}, nil
+		case 1 <= v && v <= 16:
+			return []byte{OP_1 + byte(v-1)}, nil
+		}
+		return pushData(NewScriptInt(v).Bytes()), nil
+	}
+	if strings.HasPrefix(w, "0x") {
+		b, err := hex.DecodeString(w[2:])
+		if err != nil || len(b) == 0 {
+			return nil, fmt.Errorf("bad hex")
+		}
+		return b, nil
+	}
+	if len(w) >= 2 && w[0] == '\'' && w[len(w)-1] == '\'' {
+		return MinimalPush([]byte(w[1 : len(w)-1])), nil
+	}
+	if op, ok := OpcodeByName(w); ok {
+		return []byte{op}, nil
+	}
+	var suffix []byte
+	if i := strings.IndexByte(w, '['); i > 0 && w[len(w)-1] == ']' {
+		hashType, ok := sigHashTypeByName(w[i+1 : len(w)-1])
+		if !ok {
+			return nil, fmt.Errorf("unknown sighash type")
+		}
+		suffix = []byte{byte(hashType)}
+		w = w[:i]
+	}
+	b, err := hex.DecodeString(w)
+	if err != nil || len(b) == 0 {
+		return nil, fmt.Errorf("unknown token")
+	}
+	return MinimalPush(append(b, suffix...)), nil
+}
+
+// isDecimal return true if w is a decimal number without leading
+// zeros, the way numbers are written by Disassemble
+func isDecimal(w string) bool {
+	w = strings.TrimPrefix(w, "-")
+	if w == "" || (w[0] == '0' && len(w) > 1) {
+		return false
+	}
+	for i := range w {
+		if w[i] < '0' || w[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// isDecimalHex return true if decimal number w is out of the range of
+// script numbers, but could be hex data written by Disassemble, which
+// writes pushes longer than 4 bytes as hex
+func isDecimalHex(w string) bool {
+	if w[0] == '-' || len(w)%2 != 0 || len(w) < 2*(DefaultScriptIntSize+1) {
+		return false
+	}
+	v, err := strconv.ParseInt(w, 10, 64)
+	return err != nil || v > 0xffffffff
+}
+
+func sigHashTypeByName(name string) (SigHashType, bool) {
+	for t, n := range sigHashTypeNames {
+		if n == name {
+			return t, true
+		}
+	}
+	return 0, false
+}
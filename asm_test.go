@@ -0,0 +1,110 @@
+//
+// asm_test.go
+// Copyright (C) 2017 weirdgiraffe <giraffe@cyberzoo.xyz>
+//
+// Distributed under terms of the MIT license.
+//
+
+package bitcoin
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestAssemble(t *testing.T) {
+	tt := []struct {
+		asm    string
+		script string
+	}{
+		{"", ""},
+		{
+			"OP_DUP OP_HASH160 5b6462475454710f3c22f5fdf0b40704c92f25c3 OP_EQUALVERIFY OP_CHECKSIG",
+			"76a9145b6462475454710f3c22f5fdf0b40704c92f25c388ac",
+		},
+		{"DUP HASH160 0x14 0x5b6462475454710f3c22f5fdf0b40704c92f25c3 EQUALVERIFY CHECKSIG", "76a9145b6462475454710f3c22f5fdf0b40704c92f25c388ac"},
+		{"0 -1 1 16 17 -2 1000 2147483648", "004f51600111018202e803050000008000"},
+		{"  NOP2\tNOP3\nOP_TRUE OP_FALSE ", "b1b25100"},
+		{"'' 'a' 'Az'", "00016102417a"},
+		{"00 0a0b 1f 81", "0100020a0b011f0151"},
+		{"3006020101020101[ALL]", "09300602010102010101"},
+		{"3006020101020101[SINGLE|ANYONECANPAY]", "09300602010102010183"},
+		{"0x4c 0x01 0x07", "4c0107"},
+		{"4294967295 -4294967295", "05ffffffff0005ffffffff80"},
+		{"1234567890 4294967296", "04d2029649054294967296"},
+		{"12345678901234567890", "0a12345678901234567890"},
+	}
+	for i := range tt {
+		script, err := Assemble(tt[i].asm)
+		if err != nil {
+			t.Errorf("case #%d error: %v", i+1, err)
+			continue
+		}
+		if !bytes.Equal(script, hex2byte(tt[i].script)) {
+			t.Errorf("case #%d expected %s, got %x", i+1, tt[i].script, script)
+		}
+	}
+}
+
+func TestAssembleErrors(t *testing.T) {
+	tt := []struct {
+		asm   string
+		pos   int
+		token string
+	}{
+		{"OP_DUP OP_FOO", 7, "OP_FOO"},
+		{"1  0xzz", 3, "0xzz"},
+		{"0x", 0, "0x"},
+		{"abc", 0, "abc"},
+		{"3006020101020101[FOO]", 0, "3006020101020101[FOO]"},
+		{"1 999999999999999999999", 2, "999999999999999999999"},
+		{"OP_SMALLINTEGER", 0, "OP_SMALLINTEGER"},
+		{"PUBKEYS", 0, "PUBKEYS"},
+		{"OP_PUBKEYHASH", 0, "OP_PUBKEYHASH"},
+		{"OP_PUBKEY", 0, "OP_PUBKEY"},
+		{"OP_INVALIDOPCODE", 0, "OP_INVALIDOPCODE"},
+		{"42949672960", 0, "42949672960"},
+		{"-4294967296", 0, "-4294967296"},
+	}
+	for i := range tt {
+		_, err := Assemble(tt[i].asm)
+		var ae *AsmError
+		if !errors.As(err, &ae) {
+			t.Errorf("case #%d expected AsmError, got %v", i+1, err)
+			continue
+		}
+		if ae.Pos != tt[i].pos || ae.Token != tt[i].token {
+			t.Errorf("case #%d expected %q at %d, got %v", i+1, tt[i].token, tt[i].pos, err)
+		}
+	}
+}
+
+func TestAssembleDisassemble(t *testing.T) {
+	scripts := []string{
+		"76a9145b6462475454710f3c22f5fdf0b40704c92f25c388ac",
+		"004f516002e8030500000080000c48656c6c6f20776f726c6421",
+		"09300602010102010181" + "21038479a0fa998cd35259a2ef0a7a5c68662c1474f88ccb6d08a7677bbec7f22041",
+		"6a24aa21a9ed0000000000000000000000000000000000000000000000000000000000000000",
+		"5221038479a0fa998cd35259a2ef0a7a5c68662c1474f88ccb6d08a7677bbec7f2204121038479a0fa998cd35259a2ef0a7a5c68662c1474f88ccb6d08a7677bbec7f2204152ae",
+		"059999999999",
+		"0a12345678901234567890",
+	}
+	for i := range scripts {
+		asm := Disassemble(hex2byte(scripts[i]), true)
+		script, err := Assemble(asm)
+		if err != nil {
+			t.Errorf("case #%d error: %v", i+1, err)
+			continue
+		}
+		if !bytes.Equal(script, hex2byte(scripts[i])) {
+			t.Errorf("case #%d %q assembled to %x", i+1, asm, script)
+		}
+	}
+	for op := 0; op <= 0xff; op++ {
+		name := OpcodeName(byte(op))
+		if got, ok := OpcodeByName(name); ok && got != byte(op) {
+			t.Errorf("%s maps to 0x%02x instead of 0x%02x", name, got, op)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+//
+// base58.go
+// Copyright (C) 2017 weirdgiraffe <giraffe@cyberzoo.xyz>
+//
+// Distributed under terms of the MIT license.
+//
+
+package bitcoin
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+
+	"golang.org/x/crypto/ripemd160"
+)
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+var base58Digits = func() (d [256]int8) {
+	for i := range d {
+		d[i] = -1
+	}
+	for i := range base58Alphabet {
+		d[base58Alphabet[i]] = int8(i)
+	}
+	return d
+}()
+
+// Base58Encode encodes b with bitcoin base58 alphabet, leading zero
+// bytes are encoded as '1'
+func Base58Encode(b []byte) string {
+	zeros := 0
+	for zeros < len(b) && b[zeros] == 0 {
+		zeros++
+	}
+	n := new(big.Int).SetBytes(b)
+	radix := big.NewInt(58)
+	mod := new(big.Int)
+	var ret []byte
+	for n.Sign() > 0 {
+		n.DivMod(n, radix, mod)
+		ret = append(ret, base58Alphabet[mod.Int64()])
+	}
+	for i := 0; i < zeros; i++ {
+		ret = append(ret, base58Alphabet[0])
+	}
+	for i, j := 0, len(ret)-1; i < j; i, j = i+1, j-1 {
+		ret[i], ret[j] = ret[j], ret[i]
+	}
+	return string(ret)
+}
+
+// Base58Decode decodes base58 string s
+func Base58Decode(s string) ([]byte, error) {
+	zeros := 0
+	for zeros < len(s) && s[zeros] == base58Alphabet[0] {
+		zeros++
+	}
+	n := new(big.Int)
+	radix := big.NewInt(58)
+	for i := range s {
+		d := base58Digits[s[i]]
+		if d < 0 {
+			return nil, fmt.Errorf("invalid base58 character %q at position %d: %w", s[i], i, BadAddress)
+		}
+		n.Mul(n, radix)
+		n.Add(n, big.NewInt(int64(d)))
+	}
+	return append(make([]byte, zeros), n.Bytes()...), nil
+}
+
+// Base58CheckEncode encodes version byte and payload followed by 4 byte
+// checksum, which is the beginning of double SHA256 of them
+func Base58CheckEncode(version byte, payload []byte) string {
+	b := append([]byte{version}, payload...)
+	sum := base58Checksum(b)
+	return Base58Encode(append(b, sum[:]...))
+}
+
+// Base58CheckDecode decodes string encoded by Base58CheckEncode.
+// BadChecksum is returned if checksum does not match
+func Base58CheckDecode(s string) (version byte, payload []byte, err error) {
+	b, err := Base58Decode(s)
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(b) < 5 {
+		return 0, nil, fmt.Errorf("base58check string is too short: %w", BadAddress)
+	}
+	sum := base58Checksum(b[:len(b)-4])
+	if !bytes.Equal(sum[:], b[len(b)-4:]) {
+		return 0, nil, BadChecksum
+	}
+	return b[0], b[1 : len(b)-4], nil
+}
+
+func base58Checksum(b []byte) (sum [4]byte) {
+	var h DoubleHash
+	h.Update(b)
+	copy(sum[:], h[:4])
+	return sum
+}
+
+// hash160 return RIPEMD160 of SHA256 of b, which is used as a hash of
+// public keys and scripts
+func hash160(b []byte) []byte {
+	h1 := sha256.Sum256(b)
+	h2 := ripemd160.New()
+	h2.Write(h1[:])
+	return h2.Sum(nil)
+}
@@ -0,0 +1,248 @@
+//
+// bech32.go
+// Copyright (C) 2017 weirdgiraffe <giraffe@cyberzoo.xyz>
+//
+// Distributed under terms of the MIT license.
+//
+
+package bitcoin
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Bech32Encoding is a checksum variant of bech32 string
+type Bech32Encoding int
+
+const (
+	// Bech32 is encoding defined by BIP173
+	Bech32 Bech32Encoding = iota + 1
+	// Bech32m is encoding defined by BIP350
+	Bech32m
+)
+
+const (
+	bech32Charset      = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+	bech32Const        = 1
+	bech32mConst       = 0x2bc830a3
+	bech32MaxLen       = 90
+	bech32ChecksumSize = 6
+)
+
+var bech32Digits = func() (d [256]int8) {
+	for i := range d {
+		d[i] = -1
+	}
+	for i := range bech32Charset {
+		d[bech32Charset[i]] = int8(i)
+	}
+	return d
+}()
+
+func (e Bech32Encoding) String() string {
+	switch e {
+	case Bech32:
+		return "bech32"
+	case Bech32m:
+		return "bech32m"
+	}
+	return fmt.Sprintf("Bech32Encoding(%d)", int(e))
+}
+
+func (e Bech32Encoding) checksumConst() uint32 {
+	if e == Bech32m {
+		return bech32mConst
+	}
+	return bech32Const
+}
+
+func bech32Polymod(values []byte) uint32 {
+	gen := [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	chk := uint32(1)
+	for _, v := range values {
+		b := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if (b>>uint(i))&1 == 1 {
+				chk ^= gen[i]
+			}
+		}
+	}
+	return chk
+}
+
+func bech32HRPExpand(hrp string) []byte {
+	ret := make([]byte, 0, len(hrp)*2+1)
+	for i := range hrp {
+		ret = append(ret, hrp[i]>>5)
+	}
+	ret = append(ret, 0)
+	for i := range hrp {
+		ret = append(ret, hrp[i]&31)
+	}
+	return ret
+}
+
+// Bech32Encode encodes hrp and data of 5 bit values with the given
+// checksum variant
+func Bech32Encode(hrp string, data []byte, enc Bech32Encoding) (string, error) {
+	if len(hrp)+1+len(data)+bech32ChecksumSize > bech32MaxLen {
+		return "", fmt.Errorf("bech32 string is too long: %w", BadAddress)
+	}
+	if len(hrp) == 0 {
+		return "", fmt.Errorf("bech32 hrp is empty: %w", BadAddress)
+	}
+	for i := range hrp {
+		if hrp[i] < 33 || hrp[i] > 126 {
+			return "", fmt.Errorf("invalid bech32 hrp character %q: %w", hrp[i], BadAddress)
+		}
+	}
+	hrp = strings.ToLower(hrp)
+	values := append(bech32HRPExpand(hrp), data...)
+	values = append(values, make([]byte, bech32ChecksumSize)...)
+	mod := bech32Polymod(values) ^ enc.checksumConst()
+	var sb strings.Builder
+	sb.WriteString(hrp)
+	sb.WriteByte('1')
+	for _, v := range data {
+		if v > 31 {
+			return "", fmt.Errorf("invalid bech32 value %d: %w", v, BadAddress)
+		}
+		sb.WriteByte(bech32Charset[v])
+	}
+	for i := 0; i < bech32ChecksumSize; i++ {
+		sb.WriteByte(bech32Charset[(mod>>uint(5*(5-i)))&31])
+	}
+	return sb.String(), nil
+}
+
+// Bech32Decode decodes bech32 or bech32m string s and returns its hrp,
+// data of 5 bit values and detected checksum variant.
+// BadChecksum is returned if checksum matches none of variants
+func Bech32Decode(s string) (hrp string, data []byte, enc Bech32Encoding, err error) {
+	if len(s) > bech32MaxLen {
+		return "", nil, 0, fmt.Errorf("bech32 string is too long: %w", BadAddress)
+	}
+	lower, upper := false, false
+	for i := range s {
+		c := s[i]
+		if c < 33 || c > 126 {
+			return "", nil, 0, fmt.Errorf("invalid bech32 character %q at position %d: %w", c, i, BadAddress)
+		}
+		lower = lower || (c >= 'a' && c <= 'z')
+		upper = upper || (c >= 'A' && c <= 'Z')
+	}
+	if lower && upper {
+		return "", nil, 0, fmt.Errorf("bech32 string has mixed case: %w", BadAddress)
+	}
+	s = strings.ToLower(s)
+	sep := strings.LastIndexByte(s, '1')
+	if sep < 1 || sep+1+bech32ChecksumSize > len(s) {
+		return "", nil, 0, fmt.Errorf("invalid bech32 separator position: %w", BadAddress)
+	}
+	hrp = s[:sep]
+	values := make([]byte, 0, len(s)-sep-1)
+	for i := sep + 1; i < len(s); i++ {
+		d := bech32Digits[s[i]]
+		if d < 0 {
+			return "", nil, 0, fmt.Errorf("invalid bech32 character %q at position %d: %w", s[i], i, BadAddress)
+		}
+		values = append(values, byte(d))
+	}
+	switch bech32Polymod(append(bech32HRPExpand(hrp), values...)) {
+	case bech32Const:
+		enc = Bech32
+	case bech32mConst:
+		enc = Bech32m
+	default:
+		return "", nil, 0, BadChecksum
+	}
+	return hrp, values[:len(values)-bech32ChecksumSize], enc, nil
+}
+
+// convertBits regroups data of fromBits values into toBits values
+func convertBits(data []byte, fromBits, toBits uint, pad bool) ([]byte, error) {
+	acc, bits := uint32(0), uint(0)
+	maxv := uint32(1)<<toBits - 1
+	ret := make([]byte, 0, len(data)*int(fromBits)/int(toBits)+1)
+	for _, v := range data {
+		if uint32(v)>>fromBits != 0 {
+			return nil, fmt.Errorf("invalid %d bit value %d: %w", fromBits, v, BadAddress)
+		}
+		acc = acc<<fromBits | uint32(v)
+		bits += fromBits
+		for bits >= toBits {
+			bits -= toBits
+			ret = append(ret, byte(acc>>bits&maxv))
+		}
+	}
+	if pad {
+		if bits > 0 {
+			ret = append(ret, byte(acc<<(toBits-bits)&maxv))
+		}
+	} else if bits >= fromBits || acc<<(toBits-bits)&maxv != 0 {
+		return nil, fmt.Errorf("invalid bech32 padding: %w", BadAddress)
+	}
+	return ret, nil
+}
+
+// EncodeSegWitAddress encodes witness program of the given version as
+// a segwit address, version 0 uses bech32 and others use bech32m
+func EncodeSegWitAddress(hrp string, version int, program []byte) (string, error) {
+	if err := checkWitnessProgram(version, program); err != nil {
+		return "", err
+	}
+	enc := Bech32m
+	if version == 0 {
+		enc = Bech32
+	}
+	data, err := convertBits(program, 8, 5, true)
+	if err != nil {
+		return "", err
+	}
+	return Bech32Encode(hrp, append([]byte{byte(version)}, data...), enc)
+}
+
+// DecodeSegWitAddress decodes segwit address with the expected hrp and
+// returns its witness version and program
+func DecodeSegWitAddress(hrp, addr string) (version int, program []byte, err error) {
+	gotHRP, data, enc, err := Bech32Decode(addr)
+	if err != nil {
+		return 0, nil, err
+	}
+	if gotHRP != hrp {
+		return 0, nil, fmt.Errorf("unexpected hrp %q, expected %q: %w", gotHRP, hrp, BadAddress)
+	}
+	if len(data) < 1 {
+		return 0, nil, fmt.Errorf("missing witness version: %w", BadAddress)
+	}
+	version = int(data[0])
+	if version > 16 {
+		return 0, nil, fmt.Errorf("invalid witness version %d: %w", version, BadAddress)
+	}
+	if (version == 0) != (enc == Bech32) {
+		return 0, nil, fmt.Errorf("witness version %d address uses %s: %w", version, enc, BadChecksum)
+	}
+	program, err = convertBits(data[1:], 5, 8, false)
+	if err != nil {
+		return 0, nil, err
+	}
+	if err = checkWitnessProgram(version, program); err != nil {
+		return 0, nil, err
+	}
+	return version, program, nil
+}
+
+func checkWitnessProgram(version int, program []byte) error {
+	if version < 0 || version > 16 {
+		return fmt.Errorf("invalid witness version %d: %w", version, BadAddress)
+	}
+	if len(program) < 2 || len(program) > 40 {
+		return fmt.Errorf("invalid witness program length %d: %w", len(program), BadAddress)
+	}
+	if version == 0 && len(program) != 20 && len(program) != 32 {
+		return fmt.Errorf("invalid witness v0 program length %d: %w", len(program), BadAddress)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+//
+// bech32_test.go
+// Copyright (C) 2017 weirdgiraffe <giraffe@cyberzoo.xyz>
+//
+// Distributed under terms of the MIT license.
+//
+
+package bitcoin
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// segwit address test vectors from BIP350
+func TestSegWitAddress(t *testing.T) {
+	tt := []struct {
+		addr   string
+		script string
+	}{
+		{"BC1QW508D6QEJXTDG4Y5R3ZARVARY0C5XW7KV8F3T4", "0014751e76e8199196d454941c45d1b3a323f1433bd6"},
+		{"tb1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3q0sl5k7", "00201863143c14c5166804bd19203356da136c985678cd4d27a1b8c6329604903262"},
+		{"bc1pw508d6qejxtdg4y5r3zarvary0c5xw7kw508d6qejxtdg4y5r3zarvary0c5xw7kt5nd6y", "5128751e76e8199196d454941c45d1b3a323f1433bd6751e76e8199196d454941c45d1b3a323f1433bd6"},
+		{"BC1SW50QGDZ25J", "6002751e"},
+		{"bc1zw508d6qejxtdg4y5r3zarvaryvaxxpcs", "5210751e76e8199196d454941c45d1b3a323"},
+		{"tb1qqqqqp399et2xygdj5xreqhjjvcmzhxw4aywxecjdzew6hylgvsesrxh6hy", "0020000000c4a5cad46221b2a187905e5266362b99d5e91c6ce24d165dab93e86433"},
+		{"tb1pqqqqp399et2xygdj5xreqhjjvcmzhxw4aywxecjdzew6hylgvsesf3hn0c", "5120000000c4a5cad46221b2a187905e5266362b99d5e91c6ce24d165dab93e86433"},
+		{"bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0", "512079be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798"},
+	}
+	for i := range tt {
+		hrp := strings.ToLower(tt[i].addr[:2])
+		version, program, err := DecodeSegWitAddress(hrp, tt[i].addr)
+		if err != nil {
+			t.Errorf("case #%d: unexpected error: %v", i, err)
+			continue
+		}
+		w := &WitnessAddress{Net: MainNet, Version: version, Program: program}
+		script := w.Script()
+		if !bytes.Equal(script, hex2byte(tt[i].script)) {
+			t.Errorf("case #%d: expected script %s, got %x", i, tt[i].script, script)
+		}
+		addr, err := EncodeSegWitAddress(hrp, version, program)
+		if err != nil {
+			t.Errorf("case #%d: unexpected encode error: %v", i, err)
+			continue
+		}
+		if addr != strings.ToLower(tt[i].addr) {
+			t.Errorf("case #%d: expected address %s, got %s", i, strings.ToLower(tt[i].addr), addr)
+		}
+	}
+}
+
+func TestSegWitAddressInvalid(t *testing.T) {
+	tt := []struct {
+		addr     string
+		checksum bool
+	}{
+		// invalid human readable part
+		{"tc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vq5zuyut", false},
+		// bech32 checksum for version 1+ and bech32m for version 0
+		{"bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqh2y7hd", true},
+		{"tb1z0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqglt7rf", true},
+		{"BC1S0XLXVLHEMJA6C4DQV22UAPCTQUPFHLXM9H8Z3K2E72Q4K9HCZ7VQ54WELL", true},
+		{"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kemeawh", true},
+		{"tb1q0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vq24jc47", true},
+		// bad checksum
+		{"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t5", true},
+		// invalid character
+		{"bc1p38j9r5y49hruaue7wxjce0updqjuyyx0kh56v8s25huc6995vvpql3jow4", false},
+		// invalid witness version
+		{"BC130XLXVLHEMJA6C4DQV22UAPCTQUPFHLXM9H8Z3K2E72Q4K9HCZ7VQ7ZWS8R", false},
+		// invalid program length
+		{"bc1pw5dgrnzv", false},
+		{"bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7v8n0nx0muaewav253zgeav", false},
+		{"BC1QR508D6QEJXTDG4Y5R3ZARVARYV98GJ9P", false},
+		// mixed case
+		{"tb1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vq47Zagq", false},
+		// invalid padding
+		{"bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7v07qwwzcrf", false},
+		{"tb1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vpggkg4j", false},
+		// empty data
+		{"bc1gmk9yu", false},
+	}
+	for i := range tt {
+		for _, hrp := range []string{"bc", "tb"} {
+			_, _, err := DecodeSegWitAddress(hrp, tt[i].addr)
+			if err == nil {
+				t.Errorf("case #%d: expected error for hrp %s", i, hrp)
+				continue
+			}
+			if !errors.Is(err, BadChecksum) && !errors.Is(err, BadAddress) {
+				t.Errorf("case #%d: unexpected error type: %v", i, err)
+			}
+			if hrp == strings.ToLower(tt[i].addr[:2]) && errors.Is(err, BadChecksum) != tt[i].checksum {
+				t.Errorf("case #%d: expected checksum error %v, got %v", i, tt[i].checksum, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,294 @@
+//
+// checksig.go
+// Copyright (C) 2017 weirdgiraffe <giraffe@cyberzoo.xyz>
+//
+// Distributed under terms of the MIT license.
+//
+
+package bitcoin
+
+import (
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/ecdsa"
+)
+
+// maxPubKeysPerMultiSig is the maximum number of keys for OP_CHECKMULTISIG
+const maxPubKeysPerMultiSig = 20
+
+func (e *Engine) opCheckSig(op byte) error {
+	args, err := e.main.PopN(2)
+	if err != nil {
+		return err
+	}
+	sig, pubKey := args[0], args[1]
+	if e.sigVersion == sigVersionTapscript {
+		ok, err := e.checkSigTapscript(sig, pubKey)
+		if err != nil {
+			return err
+		}
+		return e.pushCheckResult(op, ok)
+	}
+	subScript := e.script[e.codeSep:]
+	if e.sigVersion == sigVersionBase {
+		subScript, err = e.removeSignature(subScript, sig)
+		if err != nil {
+			return err
+		}
+	}
+	err = e.checkSigEncoding(sig)
+	if err != nil {
+		return err
+	}
+	err = e.checkPubKeyEncoding(pubKey)
+	if err != nil {
+		return err
+	}
+	ok := e.checkSig(sig, pubKey, subScript)
+	if !ok && len(sig) != 0 && e.flags&ScriptVerifyNullFail != 0 {
+		return scriptError(ErrSigNullFail, "%s: failed signature must be empty", OpcodeName(op))
+	}
+	return e.pushCheckResult(op, ok)
+}
+
+// pushCheckResult pushes the result of signature check to the stack
+// or fails the script if check failed for a VERIFY operation
+func (e *Engine) pushCheckResult(op byte, ok bool) error {
+	if op == OP_CHECKSIGVERIFY {
+		if !ok {
+			return scriptError(ErrCheckSigVerify, "%s failed", OpcodeName(op))
+		}
+		return nil
+	}
+	if op == OP_CHECKMULTISIGVERIFY {
+		if !ok {
+			return scriptError(ErrCheckMultiSigVerify, "%s failed", OpcodeName(op))
+		}
+		return nil
+	}
+	pushBool(&e.main, ok)
+	return nil
+}
+
+// checkSig verifies ECDSA signature with sighash type suffix against
+// the signature hash of the current input
+func (e *Engine) checkSig(sig, pubKey, subScript []byte) bool {
+	if len(sig) == 0 || e.tx == nil {
+		return false
+	}
+	hashType := SigHashType(sig[len(sig)-1])
+	s, err := ecdsa.ParseSignature(sig[:len(sig)-1])
+	if err != nil {
+		return false
+	}
+	pk, err := btcec.ParsePubKey(pubKey)
+	if err != nil {
+		return false
+	}
+	var h DoubleHash
+	if e.sigVersion == sigVersionWitnessV0 {
+		h, err = e.txSigHashes().WitnessV0SignatureHash(e.tx, subScript, e.inIndx, e.amount, hashType)
+		if err != nil {
+			return false
+		}
+	} else {
+		h = e.tx.LegacySignatureHash(subScript, e.inIndx, hashType)
+	}
+	return s.Verify(h[:], pk)
+}
+
+// opCheckMultiSig implements OP_CHECKMULTISIG and OP_CHECKMULTISIGVERIFY
+//
+// stack: <dummy> <sig1> ... <sigM> <M> <pubKey1> ... <pubKeyN> <N>
+func (e *Engine) opCheckMultiSig(op byte) error {
+	b, err := e.main.Top()
+	if err != nil {
+		return err
+	}
+	n, err := e.parseScriptInt(b, DefaultScriptIntSize)
+	if err != nil {
+		return err
+	}
+	nKeys := n.Int()
+	if nKeys < 0 || nKeys > maxPubKeysPerMultiSig {
+		return scriptError(ErrPubKeyCount, "%s: bad pubkey count %d", OpcodeName(op), nKeys)
+	}
+	err = e.countOps(nKeys)
+	if err != nil {
+		return err
+	}
+	b, err = e.main.Item(nKeys + 1)
+	if err != nil {
+		return err
+	}
+	n, err = e.parseScriptInt(b, DefaultScriptIntSize)
+	if err != nil {
+		return err
+	}
+	nSigs := n.Int()
+	if nSigs < 0 || nSigs > nKeys {
+		return scriptError(ErrSigCount, "%s: bad signature count %d", OpcodeName(op), nSigs)
+	}
+	// all arguments are removed from the stack including the dummy
+	// element, which original implementation pops by mistake
+	args, err := e.main.PopN(nKeys + nSigs + 3)
+	if err != nil {
+		return err
+	}
+	dummy := args[0]
+	sigs := args[1 : 1+nSigs]
+	pubKeys := args[2+nSigs : 2+nSigs+nKeys]
+
+	subScript := e.script[e.codeSep:]
+	if e.sigVersion == sigVersionBase {
+		for k := range sigs {
+			subScript, err = e.removeSignature(subScript, sigs[k])
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// signatures must be in the same order as their public keys, both
+	// are checked starting from the last one
+	ok := true
+	iSig, iKey := len(sigs)-1, len(pubKeys)-1
+	for ok && iSig >= 0 {
+		sig := sigs[iSig]
+		pubKey := pubKeys[iKey]
+		// encoding is checked only for the items actually used, so
+		// the order of evaluation is observable
+		err = e.checkSigEncoding(sig)
+		if err != nil {
+			return err
+		}
+		err = e.checkPubKeyEncoding(pubKey)
+		if err != nil {
+			return err
+		}
+		if e.checkSig(sig, pubKey, subScript) {
+			iSig--
+		}
+		iKey--
+		if iSig > iKey {
+			ok = false
+		}
+	}
+	if !ok && e.flags&ScriptVerifyNullFail != 0 {
+		for k := range sigs {
+			if len(sigs[k]) != 0 {
+				return scriptError(ErrSigNullFail, "%s: failed signature must be empty", OpcodeName(op))
+			}
+		}
+	}
+	if e.flags&ScriptVerifyNullDummy != 0 && len(dummy) != 0 {
+		return scriptError(ErrSigNullDummy, "%s: dummy element is not empty", OpcodeName(op))
+	}
+	return e.pushCheckResult(op, ok)
+}
+
+// removeSignature removes signature from subScript of a non-witness
+// script, which is not allowed with ScriptVerifyConstScriptCode
+func (e *Engine) removeSignature(subScript, sig []byte) ([]byte, error) {
+	ret := removeSignature(subScript, sig)
+	if len(ret) != len(subScript) && e.flags&ScriptVerifyConstScriptCode != 0 {
+		return nil, scriptError(ErrSigFindAndDelete, "Signature is found in script code")
+	}
+	return ret, nil
+}
+
+// checkSigEncoding checks that signature with sighash type suffix is
+// encoded according to the verification flags. Empty signature is
+// allowed as a compact way to provide an invalid signature
+func (e *Engine) checkSigEncoding(sig []byte) error {
+	if len(sig) == 0 {
+		return nil
+	}
+	strictDER := ScriptVerifyDERSignatures | ScriptVerifyLowS | ScriptVerifyStrictEncoding
+	if e.flags&strictDER != 0 && !isStrictDERSignature(sig) {
+		return scriptError(ErrSigDER, "Signature is not strict DER")
+	}
+	if e.flags&ScriptVerifyLowS != 0 && !isLowSSignature(sig) {
+		return scriptError(ErrSigHighS, "Signature has high S value")
+	}
+	if e.flags&ScriptVerifyStrictEncoding != 0 {
+		hashType := SigHashType(sig[len(sig)-1]) &^ SigHashAnyOneCanPay
+		if hashType < SigHashAll || hashType > SigHashSingle {
+			return scriptError(ErrSigHashType, "Signature has undefined sighash type")
+		}
+	}
+	return nil
+}
+
+// checkPubKeyEncoding checks that public key is encoded according to
+// the verification flags
+func (e *Engine) checkPubKeyEncoding(pubKey []byte) error {
+	if e.flags&ScriptVerifyStrictEncoding != 0 && !isCompressedOrUncompressedPubKey(pubKey) {
+		return scriptError(ErrPubKeyType, "Public key has unknown encoding")
+	}
+	if e.flags&ScriptVerifyWitnessPubKeyType != 0 && e.sigVersion == sigVersionWitnessV0 && !isCompressedPubKey(pubKey) {
+		return scriptError(ErrWitnessPubKeyType, "Witness public key is not compressed")
+	}
+	return nil
+}
+
+func isCompressedPubKey(pubKey []byte) bool {
+	return len(pubKey) == 33 && (pubKey[0] == 0x02 || pubKey[0] == 0x03)
+}
+
+func isCompressedOrUncompressedPubKey(pubKey []byte) bool {
+	return isCompressedPubKey(pubKey) || (len(pubKey) == 65 && pubKey[0] == 0x04)
+}
+
+// isStrictDERSignature return true if sig with sighash type suffix is
+// encoded as strict DER (BIP66):
+//
+// 0x30 <total length> 0x02 <R length> <R> 0x02 <S length> <S> <sighash>
+//
+// check https://github.com/bitcoin/bips/blob/master/bip-0066.mediawiki
+func isStrictDERSignature(sig []byte) bool {
+	if len(sig) < 9 || len(sig) > 73 {
+		return false
+	}
+	if sig[0] != 0x30 || int(sig[1]) != len(sig)-3 {
+		return false
+	}
+	lenR := int(sig[3])
+	if 5+lenR >= len(sig) {
+		return false
+	}
+	lenS := int(sig[5+lenR])
+	if lenR+lenS+7 != len(sig) {
+		return false
+	}
+	if sig[2] != 0x02 || lenR == 0 {
+		return false
+	}
+	// R must be positive and have no excessive padding
+	if sig[4]&0x80 != 0 || (lenR > 1 && sig[4] == 0 && sig[5]&0x80 == 0) {
+		return false
+	}
+	if sig[lenR+4] != 0x02 || lenS == 0 {
+		return false
+	}
+	// the same for S
+	if sig[lenR+6]&0x80 != 0 || (lenS > 1 && sig[lenR+6] == 0 && sig[lenR+7]&0x80 == 0) {
+		return false
+	}
+	return true
+}
+
+// isLowSSignature return true if S value of strict DER signature sig is
+// at most half of the curve order
+func isLowSSignature(sig []byte) bool {
+	lenR := int(sig[3])
+	s := sig[6+lenR : 6+lenR+int(sig[5+lenR])]
+	for len(s) > 0 && s[0] == 0 {
+		s = s[1:]
+	}
+	var v btcec.ModNScalar
+	if len(s) > 32 || v.SetByteSlice(s) {
+		// out of range values are not valid signatures at all
+		return true
+	}
+	return !v.IsOverHalfOrder()
+}
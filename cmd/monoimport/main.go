@@ -65,7 +65,11 @@ func main() {
 			os.Stdout.Sync()
 			docs := make([]interface{}, b.TxCount())
 			for i := 0; i < b.TxCount(); i++ {
-				docs[i] = b.Tx(i)
+				tx := b.Tx(i)
+				if err := bitcoin.CheckTransaction(tx); err != nil {
+					log.Printf("Block %s transaction %s: %v", b.Hash, tx.Hash, err)
+				}
+				docs[i] = tx
 			}
 			bulk := tc.Bulk()
 			bulk.Insert(docs...)
@@ -0,0 +1,70 @@
+//
+// disasm.go
+// Copyright (C) 2017 weirdgiraffe <giraffe@cyberzoo.xyz>
+//
+// Distributed under terms of the MIT license.
+//
+
+package bitcoin
+
+import (
+	"encoding/hex"
+	"strconv"
+	"strings"
+)
+
+// Disassemble return human readable representation of script in the
+// same format as bitcoin core decodescript RPC: data pushes up to 4
+// bytes and small integer operations are written as decimal numbers,
+// longer pushes are written as hex and other operations by their names.
+// Truncated script is terminated by [error]
+//
+// when decodeSigHash is set, pushes which look like signatures are
+// written with decoded sighash type suffix, i.e. <hex>[ALL], which is
+// useful for scriptSig
+func Disassemble(script []byte, decodeSigHash bool) string {
+	// unspendable scripts do not contain signatures
+	if len(script) > 0 && script[0] == OP_RETURN || len(script) > maxScriptSize {
+		decodeSigHash = false
+	}
+	var words []string
+	t := NewScriptTokenizer(script)
+	for t.Next() {
+		if t.Opcode() > OP_PUSHDATA4 {
+			words = append(words, asmOpcodeName(t.Opcode()))
+		} else {
+			words = append(words, asmPushData(t.Data(), decodeSigHash))
+		}
+	}
+	if t.Err() != nil {
+		words = append(words, "[error]")
+	}
+	return strings.Join(words, " ")
+}
+
+func asmPushData(data []byte, decodeSigHash bool) string {
+	if len(data) <= DefaultScriptIntSize {
+		return strconv.Itoa(ScriptIntFromSlice(data).Int())
+	}
+	if decodeSigHash && isStrictDERSignature(data) {
+		hashType := SigHashType(data[len(data)-1])
+		if _, ok := sigHashTypeNames[hashType]; ok {
+			return hex.EncodeToString(data[:len(data)-1]) + "[" + hashType.String() + "]"
+		}
+	}
+	return hex.EncodeToString(data)
+}
+
+// asmOpcodeName return name of operation as it is written by bitcoin
+// core, which uses numbers for small integer operations
+func asmOpcodeName(op byte) string {
+	switch {
+	case op == OP_1NEGATE:
+		return "-1"
+	case OP_1 <= op && op <= OP_16:
+		return strconv.Itoa(int(op - OP_1 + 1))
+	case op > OP_CHECKSIGADD && op != OP_INVALIDOPCODE:
+		return "OP_UNKNOWN"
+	}
+	return OpcodeName(op)
+}
@@ -0,0 +1,63 @@
+//
+// disasm_test.go
+// Copyright (C) 2017 weirdgiraffe <giraffe@cyberzoo.xyz>
+//
+// Distributed under terms of the MIT license.
+//
+
+package bitcoin
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDisassemble(t *testing.T) {
+	sig := "3006020101020101"
+	pubKey := "038479a0fa998cd35259a2ef0a7a5c68662c1474f88ccb6d08a7677bbec7f22041"
+	tt := []struct {
+		script        string
+		decodeSigHash bool
+		asm           string
+	}{
+		{"", false, ""},
+		{
+			"76a9145b6462475454710f3c22f5fdf0b40704c92f25c388ac", false,
+			"OP_DUP OP_HASH160 5b6462475454710f3c22f5fdf0b40704c92f25c3 OP_EQUALVERIFY OP_CHECKSIG",
+		},
+		{"00514f60", false, "0 1 -1 16"},
+		{"0101018102ff0004ffffff7f", false, "1 -1 255 2147483647"},
+		{"0500000000ff", false, "00000000ff"},
+		{"4c0101", false, "1"},
+		{"4d0200ff00", false, "255"},
+		{"4e01000000aa", false, "-42"},
+		{"09" + sig + "01" + "21" + pubKey, true, sig + "[ALL] " + pubKey},
+		{"09" + sig + "81", true, sig + "[ALL|ANYONECANPAY]"},
+		{"09" + sig + "03", true, sig + "[SINGLE]"},
+		{"09" + sig + "01", false, sig + "01"},
+		{"09" + sig + "05", true, sig + "05"},
+		{"6a09" + sig + "01", true, "OP_RETURN " + sig + "01"},
+		{"b1b275", false, "OP_CHECKLOCKTIMEVERIFY OP_CHECKSEQUENCEVERIFY OP_DROP"},
+		{"50bbff", false, "OP_RESERVED OP_UNKNOWN OP_INVALIDOPCODE"},
+		{"5105aabb", false, "1 [error]"},
+		{"4c", false, "[error]"},
+		{"4d01", false, "[error]"},
+	}
+	for i := range tt {
+		asm := Disassemble(hex2byte(tt[i].script), tt[i].decodeSigHash)
+		if asm != tt[i].asm {
+			t.Errorf("case #%d expected %q, got %q", i+1, tt[i].asm, asm)
+		}
+	}
+}
+
+func TestTxOutJSON(t *testing.T) {
+	out := TxOut{Value: 1, Script: hex2byte("6a0401020304")}
+	if !strings.Contains(out.String(), `"ScriptASM": "OP_RETURN 67305985"`) {
+		t.Errorf("unexpected JSON:\n%s", out)
+	}
+	in := TxIn{Script: hex2byte("09300602010102010101")}
+	if !strings.Contains(in.String(), `"ScriptASM": "3006020101020101[ALL]"`) {
+		t.Errorf("unexpected JSON:\n%s", in)
+	}
+}
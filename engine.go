@@ -0,0 +1,421 @@
+//
+// engine.go
+// Copyright (C) 2017 weirdgiraffe <giraffe@cyberzoo.xyz>
+//
+// Distributed under terms of the MIT license.
+//
+
+package bitcoin
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// BadInputIndex is returned when input to verify is not in transaction
+var BadInputIndex = errors.New("Bad input index")
+
+// sigVersion defines how signature hash is computed by signature checks
+type sigVersion int
+
+const (
+	sigVersionBase sigVersion = iota
+	sigVersionWitnessV0
+	sigVersionTaproot
+	sigVersionTapscript
+)
+
+// maxOpsPerScript is the maximum number of non-push operations per script
+const maxOpsPerScript = 201
+
+// Engine executes scripts in the context of a single transaction input
+type Engine struct {
+	tx       *Tx
+	inIndx   int
+	flags    ScriptFlags
+	prevOuts PrevOutFetcher
+
+	sigVersion sigVersion
+	// amount of the output spent by the input, is known only for
+	// witness programs
+	amount uint64
+
+	main Stack
+	alt  Stack
+	cond condStack
+
+	// script that is being executed and offset right after the last
+	// executed OP_CODESEPARATOR
+	script  []byte
+	codeSep int
+	opCount int
+
+	// hashes of transaction parts, which could be shared by engines of
+	// all transaction inputs
+	sigHashes *TxSigHashes
+
+	// taproot spending context: outputs spent by all inputs, annex and
+	// leaf hash of the executed script, remaining validation weight
+	// budget and opcode position of the last executed OP_CODESEPARATOR
+	spentOuts        []*TxOut
+	annex            []byte
+	tapLeafHash      [32]byte
+	validationWeight int64
+	codeSepPos       uint32
+}
+
+// NewEngine creates engine for input inIndx of tx. prevOuts is used to
+// look up amounts of spent outputs and could be nil if tx has no
+// witness inputs. tx could be nil to verify scripts without signature
+// and lock time checks
+func NewEngine(tx *Tx, inIndx int, flags ScriptFlags, prevOuts PrevOutFetcher) *Engine {
+	e := &Engine{
+		tx:       tx,
+		inIndx:   inIndx,
+		flags:    flags,
+		prevOuts: prevOuts,
+	}
+	return e
+}
+
+// Stack return main stack of the engine, which is shared by scripts
+// executed one after another
+func (e *Engine) Stack() *Stack {
+	return &e.main
+}
+
+// VerifyScript checks that scriptSig of input inIndx of tx satisfies
+// scriptPubKey of the output it spends
+//
+// return nil if input is valid
+func VerifyScript(scriptSig, scriptPubKey []byte, tx *Tx, inIndx int, flags ScriptFlags) error {
+	return NewEngine(tx, inIndx, flags, nil).Verify(scriptSig, scriptPubKey)
+}
+
+// VerifyInput checks that input inIndx of tx (including its witness)
+// satisfies the output it spends, which is looked up with prevOuts
+//
+// return nil if input is valid
+func VerifyInput(tx *Tx, inIndx int, flags ScriptFlags, prevOuts PrevOutFetcher) error {
+	err := checkInputIndex(tx, inIndx)
+	if err != nil {
+		return err
+	}
+	if prevOuts == nil {
+		return noPrevOutFetcher()
+	}
+	in := &tx.In[inIndx]
+	out, err := prevOuts.FetchPrevOut(in.PrevTx, in.PrevTxOutIndx)
+	if err != nil {
+		return err
+	}
+	return NewEngine(tx, inIndx, flags, prevOuts).Verify(in.Script, out.Script)
+}
+
+// VerifyTx checks that all inputs of tx satisfy the outputs they spend.
+// Spent outputs are looked up once and hashes of transaction parts are
+// shared by signature checks of all inputs
+//
+// return nil if all inputs are valid
+func VerifyTx(tx *Tx, flags ScriptFlags, prevOuts PrevOutFetcher) error {
+	if tx == nil {
+		return fmt.Errorf("%w: transaction is nil", BadInputIndex)
+	}
+	if prevOuts == nil {
+		return noPrevOutFetcher()
+	}
+	spentOuts := make([]*TxOut, len(tx.In))
+	for i := range tx.In {
+		out, err := prevOuts.FetchPrevOut(tx.In[i].PrevTx, tx.In[i].PrevTxOutIndx)
+		if err != nil {
+			return fmt.Errorf("input %d: %w", i, err)
+		}
+		spentOuts[i] = out
+	}
+	sigHashes := NewTxSigHashes(tx, spentOuts)
+	for i := range tx.In {
+		e := NewEngine(tx, i, flags, prevOuts)
+		e.spentOuts = spentOuts
+		e.sigHashes = sigHashes
+		err := e.Verify(tx.In[i].Script, spentOuts[i].Script)
+		if err != nil {
+			return fmt.Errorf("input %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// checkInputIndex return BadInputIndex error if tx has no input inIndx
+func checkInputIndex(tx *Tx, inIndx int) error {
+	if tx == nil {
+		return fmt.Errorf("%w %d: transaction is nil", BadInputIndex, inIndx)
+	}
+	if inIndx < 0 || inIndx >= len(tx.In) {
+		return fmt.Errorf("%w %d: transaction has %d inputs", BadInputIndex, inIndx, len(tx.In))
+	}
+	return nil
+}
+
+// txSigHashes return hashes of transaction parts, which are computed on
+// the first use unless they are shared with other inputs
+func (e *Engine) txSigHashes() *TxSigHashes {
+	if e.sigHashes == nil {
+		e.sigHashes = NewTxSigHashes(e.tx, e.spentOuts)
+	}
+	return e.sigHashes
+}
+
+// Verify executes scriptSig and then scriptPubKey on the same main stack
+// and checks that the result is true. For pay-to-script-hash outputs
+// redeem script is executed as well if ScriptVerifyP2SH flag is set and
+// witness programs are verified if ScriptVerifyWitness flag is set
+func (e *Engine) Verify(scriptSig, scriptPubKey []byte) error {
+	var witness [][]byte
+	if e.tx != nil {
+		err := checkInputIndex(e.tx, e.inIndx)
+		if err != nil {
+			return err
+		}
+		witness = e.tx.In[e.inIndx].Witness
+	}
+	if e.flags&ScriptVerifySigPushOnly != 0 && !IsPushOnly(scriptSig) {
+		return scriptError(ErrSigPushOnly, "scriptSig is not push only")
+	}
+	e.sigVersion = sigVersionBase
+	e.main.Reset()
+	err := e.Execute(scriptSig)
+	if err != nil {
+		return err
+	}
+	// redeem script is executed on the stack left by scriptSig
+	p2sh := e.flags&ScriptVerifyP2SH != 0 && IsPayToScriptHash(scriptPubKey)
+	var saved [][]byte
+	if p2sh {
+		saved = e.main.Items()
+	}
+	err = e.Execute(scriptPubKey)
+	if err != nil {
+		return err
+	}
+	if !e.topIsTrue() {
+		return scriptError(ErrEvalFalse, "Script evaluated without error but finished with a false/empty top stack element")
+	}
+	hadWitness := false
+	if e.flags&ScriptVerifyWitness != 0 {
+		if version, program, ok := WitnessProgram(scriptPubKey); ok {
+			hadWitness = true
+			if len(scriptSig) != 0 {
+				return scriptError(ErrWitnessMalleated, "Witness program requires empty scriptSig")
+			}
+			err = e.verifyWitnessProgram(witness, version, program, false)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if p2sh {
+		if !IsPushOnly(scriptSig) {
+			return scriptError(ErrSigPushOnly, "P2SH scriptSig is not push only")
+		}
+		e.main.Reset()
+		for i := range saved {
+			e.main.PushSlice(saved[i])
+		}
+		// scriptSig is push only and evaluated to true, so the stack
+		// is not empty
+		redeemScript, _ := e.main.Pop()
+		err = e.Execute(redeemScript)
+		if err != nil {
+			return err
+		}
+		if !e.topIsTrue() {
+			return scriptError(ErrEvalFalse, "Script evaluated without error but finished with a false/empty top stack element")
+		}
+		if e.flags&ScriptVerifyWitness != 0 {
+			if version, program, ok := WitnessProgram(redeemScript); ok {
+				hadWitness = true
+				if !bytes.Equal(scriptSig, pushData(redeemScript)) {
+					return scriptError(ErrWitnessMalleatedP2SH, "P2SH witness program scriptSig must be a single push of redeem script")
+				}
+				err = e.verifyWitnessProgram(witness, version, program, true)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	if e.flags&ScriptVerifyWitness != 0 && !hadWitness && len(witness) != 0 {
+		return scriptError(ErrWitnessUnexpected, "Unexpected witness for non witness program")
+	}
+	// witness scripts are checked to leave exactly one item on their
+	// own stack
+	if e.flags&ScriptVerifyCleanStack != 0 && !hadWitness && e.main.Len() != 1 {
+		return scriptError(ErrCleanStack, "Script must leave exactly one item on the stack")
+	}
+	return nil
+}
+
+// Execute runs script on top of the current main stack. Alt stack is
+// not shared between scripts
+func (e *Engine) Execute(script []byte) (err error) {
+	if e.tx != nil {
+		err = checkInputIndex(e.tx, e.inIndx)
+		if err != nil {
+			return err
+		}
+	}
+	// tapscript size is limited only by the block weight
+	if e.sigVersion != sigVersionTapscript && len(script) > maxScriptSize {
+		return scriptError(ErrScriptSize, "Script is bigger than %d bytes", maxScriptSize)
+	}
+	e.alt.Reset()
+	e.cond.Reset()
+	e.script = script
+	e.codeSep = 0
+	e.opCount = 0
+	e.codeSepPos = 0xffffffff
+	t := NewScriptTokenizer(script)
+	for opPos := uint32(0); t.Next(); opPos++ {
+		op := t.Opcode()
+		if op == OP_CODESEPARATOR && e.sigVersion == sigVersionBase && e.flags&ScriptVerifyConstScriptCode != 0 {
+			return withOffset(scriptError(ErrOpCodeSeparator, "OP_CODESEPARATOR is not allowed in non-witness script"), t.Offset())
+		}
+		// tapscript has no limit on the number of operations
+		if op > OP_16 && e.sigVersion != sigVersionTapscript {
+			err = e.countOps(1)
+			if err != nil {
+				return withOffset(err, t.Offset())
+			}
+		}
+		if IsDisabledOpcode(op) {
+			return withOffset(disabledOpcode(op), t.Offset())
+		}
+		// pushes are checked even if they are not executed
+		if len(t.Data()) > maxScriptElementSize {
+			return withOffset(scriptError(ErrPushSize, "Push is bigger than %d bytes", maxScriptElementSize), t.Offset())
+		}
+		if e.cond.AllTrue() || (OP_IF <= op && op <= OP_ENDIF) {
+			err = e.step(op, t.Data())
+			if err != nil {
+				return withOffset(err, t.Offset())
+			}
+			if op == OP_CODESEPARATOR {
+				e.codeSep = t.End()
+				e.codeSepPos = opPos
+			}
+		}
+		if e.main.Len()+e.alt.Len() > maxStackSize {
+			return withOffset(scriptError(ErrStackSize, "Stack has more than %d items", maxStackSize), t.Offset())
+		}
+	}
+	if t.Err() != nil {
+		return t.Err()
+	}
+	if !e.cond.Empty() {
+		return scriptError(ErrUnbalancedConditional, "Unbalanced conditional")
+	}
+	return nil
+}
+
+// step executes a single operation, data is pushed by push operations
+func (e *Engine) step(op byte, data []byte) (err error) {
+	switch {
+	case op <= OP_PUSHDATA4:
+		if e.flags&ScriptVerifyMinimalData != 0 && !isMinimalPush(op, data) {
+			return scriptError(ErrMinimalData, "Data is not pushed with minimal push operation")
+		}
+		e.main.PushSlice(data)
+	case op <= OP_16 && op != OP_RESERVED:
+		_, err = OpConstants(op, nil, &e.main)
+	case op == OP_NOP:
+	case op == OP_NOP1, OP_NOP4 <= op && op <= OP_NOP10:
+		err = e.upgradableNop(op)
+	case op == OP_RESERVED, op == OP_VER, op == OP_RESERVED1, op == OP_RESERVED2:
+		err = scriptError(ErrBadOpcode, "%s is reserved", OpcodeName(op))
+	case op == OP_IF, op == OP_NOTIF:
+		// tapscript requires argument of OP_IF to be exactly empty or 1,
+		// for witness v0 scripts it is a policy rule
+		minimalIf := e.sigVersion == sigVersionTapscript ||
+			(e.sigVersion == sigVersionWitnessV0 && e.flags&ScriptVerifyMinimalIf != 0)
+		if minimalIf && e.cond.AllTrue() {
+			top, err := e.main.Top()
+			if err != nil {
+				return scriptError(ErrInvalidStackOperation, "%s without argument", OpcodeName(op))
+			}
+			if len(top) > 1 || (len(top) == 1 && top[0] != 1) {
+				code := ErrMinimalIf
+				if e.sigVersion == sigVersionTapscript {
+					code = ErrTapscriptMinimalIf
+				}
+				return scriptError(code, "%s argument must be minimal", OpcodeName(op))
+			}
+		}
+		err = OpFlowControl(op, &e.main, &e.cond)
+	case OP_IF <= op && op <= OP_RETURN:
+		err = OpFlowControl(op, &e.main, &e.cond)
+	case op == OP_CHECKLOCKTIMEVERIFY:
+		// when the rule is not enabled the opcode is OP_NOP2, which is
+		// not discouraged the same as in bitcoin core
+		if e.flags&ScriptVerifyCheckLockTimeVerify != 0 {
+			err = e.opCheckLockTimeVerify()
+		}
+	case op == OP_CHECKSEQUENCEVERIFY:
+		if e.flags&ScriptVerifyCheckSequenceVerify != 0 {
+			err = e.opCheckSequenceVerify()
+		}
+	case OP_TOALTSTACK <= op && op <= OP_TUCK:
+		err = OpStack(op, &e.main, &e.alt, e.flags&ScriptVerifyMinimalData != 0)
+	case OP_CAT <= op && op <= OP_SIZE:
+		err = OpSplice(op, &e.main, &e.alt)
+	case OP_INVERT <= op && op <= OP_EQUALVERIFY:
+		err = OpBitwise(op, &e.main, &e.alt)
+	case OP_1ADD <= op && op <= OP_WITHIN:
+		err = OpArithmetic(op, &e.main, &e.alt, e.flags&ScriptVerifyMinimalData != 0)
+	case OP_RIPEMD160 <= op && op <= OP_HASH256:
+		err = OpCrypto(op, &e.main, &e.alt)
+	case op == OP_CODESEPARATOR:
+	case op == OP_CHECKSIG, op == OP_CHECKSIGVERIFY:
+		err = e.opCheckSig(op)
+	case op == OP_CHECKMULTISIG, op == OP_CHECKMULTISIGVERIFY:
+		if e.sigVersion == sigVersionTapscript {
+			return scriptError(ErrTapscriptCheckMultiSig, "%s is disabled in tapscript", OpcodeName(op))
+		}
+		err = e.opCheckMultiSig(op)
+	case op == OP_CHECKSIGADD && e.sigVersion == sigVersionTapscript:
+		err = e.opCheckSigAdd()
+	default:
+		err = scriptError(ErrBadOpcode, "%s is not supported", OpcodeName(op))
+	}
+	return err
+}
+
+// upgradableNop executes OP_NOPx reserved for soft forks, which could
+// be discouraged to avoid relaying transactions that might become
+// invalid after an upgrade
+func (e *Engine) upgradableNop(op byte) error {
+	if e.flags&ScriptVerifyDiscourageUpgradableNops != 0 {
+		return scriptError(ErrDiscourageUpgradableNops, "%s is reserved for soft-fork upgrades", OpcodeName(op))
+	}
+	return nil
+}
+
+func (e *Engine) countOps(n int) error {
+	e.opCount += n
+	if e.opCount > maxOpsPerScript {
+		return scriptError(ErrOpCount, "Script has more than %d operations", maxOpsPerScript)
+	}
+	return nil
+}
+
+// topIsTrue return true if the top item of main stack is true
+func (e *Engine) topIsTrue() bool {
+	b, err := e.main.Top()
+	return err == nil && slice2bool(b)
+}
+
+// parseScriptInt decodes number operand of the operation, which must be
+// minimally encoded if MINIMALDATA rule is enabled
+func (e *Engine) parseScriptInt(b []byte, maxSize int) (ScriptInt, error) {
+	return ParseScriptInt(b, maxSize, e.flags&ScriptVerifyMinimalData != 0)
+}
@@ -0,0 +1,444 @@
+//
+// engine_test.go
+// Copyright (C) 2017 weirdgiraffe <giraffe@cyberzoo.xyz>
+//
+// Distributed under terms of the MIT license.
+//
+
+package bitcoin
+
+import (
+	"crypto/sha256"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestVerifyScript(t *testing.T) {
+	tt := []struct {
+		scriptSig    string
+		scriptPubKey string
+		expect_err   bool
+	}{
+		{"", "51", false},
+		{"", "00", true},
+		{"", "", true},
+		{"5152", "935387", false},
+		{"5152", "935487", true},
+		{"0102", "0102", false},
+		{"02abcd", "76a914" + "a9e9e5c0f0ae1d6a1a6f3ba1e5aa8d4c0a1c6a67" + "87", true},
+		{"0101", "76879169", true},
+		{"4c02abcd", "02abcd87", false},
+		{"4d0200abcd", "02abcd87", false},
+		{"4e02000000abcd", "02abcd87", false},
+		{"51", "6b6c", false},
+		{"51", "6c", true},
+		{"4c", "51", true},
+		{"51", "50", true},
+		{"51", "6351675168", false},
+		{"00", "6300675168", false},
+		{"00", "63006768", true},
+		{"51", "6451670068", true},
+		{"51", "63006350686851", false},
+		{"51", "63006365686851", true},
+		{"51", "63006366686851", true},
+		{"51", "63", true},
+		{"51", "68", true},
+		{"51", "6351", true},
+		{"5163", "5168", true},
+		{"51", "6751", true},
+		{"51", "6367675168", false},
+		{"51", "5169", false},
+		{"51", "0069", true},
+		{"51", "6a", true},
+		{"00", "636a6851", false},
+	}
+	for i := range tt {
+		err := VerifyScript(hex2byte(tt[i].scriptSig), hex2byte(tt[i].scriptPubKey), nil, 0, ScriptVerifyNone)
+		if err != nil && tt[i].expect_err == false {
+			t.Errorf("case #%d error: %v", i+1, err)
+		}
+		if err == nil && tt[i].expect_err == true {
+			t.Errorf("case #%d expected to fail", i+1)
+		}
+	}
+}
+
+// cases are taken from bitcoin core src/test/data/tx_valid.json
+func TestVerifyScriptCheckSig(t *testing.T) {
+	tt := []struct {
+		scriptPubKey string
+		tx           string
+	}{
+		{
+			"76a914dc44b1164188067c3a32d4780f5996fa14a4f2d988ac",
+			"01000000010276b76b07f4935c70acf54fbf1f438a4c397a9fb7e633873c4dd3bc062b6b40000000008c493046022100d23459d03ed7e9511a47d13292d3430a04627de6235b6e51a40f9cd386f2abe3022100e7d25b080f0bb8d8d5f878bba7d54ad2fda650ea8d158a33ee3cbd11768191fd004104b0e2c879e4daf7b9ab68350228c159766676a14f5815084ba166432aab46198d4cca98fa3e9981d0a90b2effc514b76279476550ba3663fdcaff94c38420e9d5000000000100093d00000000001976a9149a7b0f3b80c6baaeedce0a0842553800f832ba1f88ac00000000",
+		},
+		{
+			"514104cc71eb30d653c0c3163990c47b976f3fb3f37cccdcbedb169a1dfef58bbfbfaff7d8a473e7e2e6d317b87bafe8bde97e3cf8f065dec022b51d11fcdd0d348ac4410461cbdcc5409fb4b4d42b51d33381354d80e550078cb532a34bfa2fcfdeb7d76519aecc62770f5b0e4ef8551946d8a540911abe3e7854a26f39f58b25c15342af52ae",
+			"0100000001b14bdcbc3e01bdaad36cc08e81e69c82e1060bc14e518db2b49aa43ad90ba26000000000490047304402203f16c6f40162ab686621ef3000b04e75418a0c0cb2d8aebeac894ae360ac1e780220ddc15ecdfc3507ac48e1681a33eb60996631bf6bf5bc0a0682c4db743ce7ca2b01ffffffff0140420f00000000001976a914660d4ef3a743e3e696ad990364e555c271ad504b88ac00000000",
+		},
+		{
+			"514104cc71eb30d653c0c3163990c47b976f3fb3f37cccdcbedb169a1dfef58bbfbfaff7d8a473e7e2e6d317b87bafe8bde97e3cf8f065dec022b51d11fcdd0d348ac4410461cbdcc5409fb4b4d42b51d33381354d80e550078cb532a34bfa2fcfdeb7d76519aecc62770f5b0e4ef8551946d8a540911abe3e7854a26f39f58b25c15342af52ae",
+			"0100000001b14bdcbc3e01bdaad36cc08e81e69c82e1060bc14e518db2b49aa43ad90ba26000000000494f47304402203f16c6f40162ab686621ef3000b04e75418a0c0cb2d8aebeac894ae360ac1e780220ddc15ecdfc3507ac48e1681a33eb60996631bf6bf5bc0a0682c4db743ce7ca2b01ffffffff0140420f00000000001976a914660d4ef3a743e3e696ad990364e555c271ad504b88ac00000000",
+		},
+		{
+			"ab21038479a0fa998cd35259a2ef0a7a5c68662c1474f88ccb6d08a7677bbec7f22041ac",
+			"01000000012432b60dc72cebc1a27ce0969c0989c895bdd9e62e8234839117f8fc32d17fbc000000004a493046022100a576b52051962c25e642c0fd3d77ee6c92487048e5d90818bcf5b51abaccd7900221008204f8fb121be4ec3b24483b1f92d89b1b0548513a134e345c5442e86e8617a501ffffffff010000000000000000016a00000000",
+		},
+		{
+			"21038479a0fa998cd35259a2ef0a7a5c68662c1474f88ccb6d08a7677bbec7f22041abac",
+			"01000000015ebaa001d8e4ec7a88703a3bcf69d98c874bca6299cca0f191512bf2a7826832000000004948304502203bf754d1c6732fbf87c5dcd81258aefd30f2060d7bd8ac4a5696f7927091dad1022100f5bcb726c4cf5ed0ed34cc13dadeedf628ae1045b7cb34421bc60b89f4cecae701ffffffff010000000000000000016a00000000",
+		},
+	}
+	for i := range tt {
+		tx := hex2tx(t, tt[i].tx)
+		err := VerifyScript(tx.In[0].Script, hex2byte(tt[i].scriptPubKey), tx, 0, ScriptVerifyNone)
+		if err != nil {
+			t.Errorf("case #%d error: %v", i+1, err)
+		}
+		// any change of the transaction invalidates the signature
+		tx.LockTime++
+		err = VerifyScript(tx.In[0].Script, hex2byte(tt[i].scriptPubKey), tx, 0, ScriptVerifyNone)
+		if err == nil {
+			t.Errorf("case #%d expected to fail for modified transaction", i+1)
+		}
+	}
+}
+
+func TestVerifyScriptCheckMultiSig(t *testing.T) {
+	tt := []struct {
+		scriptSig    string
+		scriptPubKey string
+		flags        ScriptFlags
+		expect_err   bool
+	}{
+		{"00", "0000ae", ScriptVerifyNone, false},
+		{"00", "0000af51", ScriptVerifyNone, false},
+		{"51", "0000ae", ScriptVerifyNone, false},
+		{"51", "0000ae", ScriptVerifyNullDummy, true},
+		{"", "0000ae", ScriptVerifyNone, true},
+		{"00", "0021038479a0fa998cd35259a2ef0a7a5c68662c1474f88ccb6d08a7677bbec7f2204151ae", ScriptVerifyNone, false},
+		{"0000", "5121038479a0fa998cd35259a2ef0a7a5c68662c1474f88ccb6d08a7677bbec7f2204151ae", ScriptVerifyNone, true},
+		{"0000", "5121038479a0fa998cd35259a2ef0a7a5c68662c1474f88ccb6d08a7677bbec7f2204151ae69", ScriptVerifyNone, true},
+		{"00", "520115ae", ScriptVerifyNone, true},
+		{"00", "5200ae", ScriptVerifyNone, true},
+		{"00", strings.Repeat("61", 199) + "0021038479a0fa998cd35259a2ef0a7a5c68662c1474f88ccb6d08a7677bbec7f2204151ae", ScriptVerifyNone, false},
+		{"00", strings.Repeat("61", 200) + "0021038479a0fa998cd35259a2ef0a7a5c68662c1474f88ccb6d08a7677bbec7f2204151ae", ScriptVerifyNone, true},
+	}
+	for i := range tt {
+		err := VerifyScript(hex2byte(tt[i].scriptSig), hex2byte(tt[i].scriptPubKey), nil, 0, tt[i].flags)
+		if err != nil && tt[i].expect_err == false {
+			t.Errorf("case #%d error: %v", i+1, err)
+		}
+		if err == nil && tt[i].expect_err == true {
+			t.Errorf("case #%d expected to fail", i+1)
+		}
+	}
+}
+
+func TestVerifyScriptP2SH(t *testing.T) {
+	tt := []struct {
+		scriptSig    string
+		scriptPubKey string
+		flags        ScriptFlags
+		expect_err   bool
+	}{
+		{"0151", "a914da1745e9b549bd0bfa1a569971c77eba30cd5a4b87", ScriptVerifyP2SH, false},
+		{"0151", "a914da1745e9b549bd0bfa1a569971c77eba30cd5a4b87", ScriptVerifyNone, false},
+		{"0152", "a914da1745e9b549bd0bfa1a569971c77eba30cd5a4b87", ScriptVerifyP2SH, true},
+		{"0100", "a9149f7fd096d37ed2c0e3f7f0cfc924beef4ffceb6887", ScriptVerifyNone, false},
+		{"0100", "a9149f7fd096d37ed2c0e3f7f0cfc924beef4ffceb6887", ScriptVerifyP2SH, true},
+		{"51750151", "a914da1745e9b549bd0bfa1a569971c77eba30cd5a4b87", ScriptVerifyNone, false},
+		{"51750151", "a914da1745e9b549bd0bfa1a569971c77eba30cd5a4b87", ScriptVerifyP2SH, true},
+		{"000151", "a914da1745e9b549bd0bfa1a569971c77eba30cd5a4b87", ScriptVerifyP2SH, false},
+		{"0151", "a914da1745e9b549bd0bfa1a569971c77eba30cd5a4b8788", ScriptVerifyP2SH, true},
+	}
+	for i := range tt {
+		err := VerifyScript(hex2byte(tt[i].scriptSig), hex2byte(tt[i].scriptPubKey), nil, 0, tt[i].flags)
+		if err != nil && tt[i].expect_err == false {
+			t.Errorf("case #%d error: %v", i+1, err)
+		}
+		if err == nil && tt[i].expect_err == true {
+			t.Errorf("case #%d expected to fail", i+1)
+		}
+	}
+}
+
+func TestVerifyScriptFlags(t *testing.T) {
+	pubKey := "21038479a0fa998cd35259a2ef0a7a5c68662c1474f88ccb6d08a7677bbec7f22041"
+	highS := "2830250201010220" + "7f" + strings.Repeat("ff", 31) + "01"
+	tt := []struct {
+		scriptSig    string
+		scriptPubKey string
+		flags        ScriptFlags
+		expect_err   bool
+	}{
+		{"5161", "51", ScriptVerifyNone, false},
+		{"5161", "51", ScriptVerifySigPushOnly, true},
+		{"0101", "51", ScriptVerifyNone, false},
+		{"0101", "51", ScriptVerifyMinimalData, true},
+		{"0181", "51", ScriptVerifyMinimalData, true},
+		{"4c0102", "51", ScriptVerifyMinimalData, true},
+		{"020102", "51", ScriptVerifyMinimalData, false},
+		{"020100", "8b5287", ScriptVerifyNone, false},
+		{"020100", "8b5287", ScriptVerifyMinimalData, true},
+		{"", "51018079", ScriptVerifyNone, false},
+		{"", "51018079", ScriptVerifyMinimalData, true},
+		{"", "0063506851", ScriptVerifyNone, false},
+		{"", "0063626851", ScriptVerifyNone, false},
+		{"", "51b0b3b9", ScriptVerifyNone, false},
+		{"", "0063b9b16851", ScriptVerifyDiscourageUpgradableNops, false},
+		{"", "51b9", ScriptVerifyDiscourageUpgradableNops, true},
+		{"", "51b2", ScriptVerifyNone, false},
+		{"", "51b2", ScriptVerifyDiscourageUpgradableNops, false},
+		{"", "51b1", ScriptVerifyDiscourageUpgradableNops, false},
+		{"5151", "51", ScriptVerifyP2SH | ScriptVerifyWitness, false},
+		{"5151", "51", ScriptVerifyP2SH | ScriptVerifyWitness | ScriptVerifyCleanStack, true},
+		{"", "51", ScriptVerifyP2SH | ScriptVerifyWitness | ScriptVerifyCleanStack, false},
+		{"51", "ab51", ScriptVerifyNone, false},
+		{"51", "ab51", ScriptVerifyConstScriptCode, true},
+		{"51", "0068ab6851", ScriptVerifyConstScriptCode, true},
+		// empty signature is removed as OP_0 from script code
+		{"00", "007551ac91", ScriptVerifyNone, false},
+		{"00", "007551ac91", ScriptVerifyConstScriptCode, true},
+		{"0101", pubKey + "ac91", ScriptVerifyNone, false},
+		{"0101", pubKey + "ac91", ScriptVerifyNullFail, true},
+		{"00", pubKey + "ac91", ScriptVerifyNullFail, false},
+		{"0000", "51010551ae91", ScriptVerifyNone, false},
+		{"0000", "51010551ae91", ScriptVerifyStrictEncoding, true},
+		{"0000", "51" + pubKey + "51ae91", ScriptVerifyNullFail, false},
+		{"000101", "51" + pubKey + "51ae91", ScriptVerifyNullFail, true},
+		{"00", "0105ac91", ScriptVerifyNone, false},
+		{"00", "0105ac91", ScriptVerifyStrictEncoding, true},
+		{"093006020101020101" + "01", pubKey + "ac91", ScriptVerifyDERSignatures, false},
+		{"0a30070202000102010101", pubKey + "ac91", ScriptVerifyNone, false},
+		{"0a30070202000102010101", pubKey + "ac91", ScriptVerifyDERSignatures, true},
+		{highS, pubKey + "ac91", ScriptVerifyDERSignatures, false},
+		{highS, pubKey + "ac91", ScriptVerifyLowS, true},
+		{"093006020101020101" + "05", pubKey + "ac91", ScriptVerifyDERSignatures, false},
+		{"093006020101020101" + "05", pubKey + "ac91", ScriptVerifyStrictEncoding, true},
+		{"093006020101020101" + "81", pubKey + "ac91", ScriptVerifyStrictEncoding, false},
+	}
+	for i := range tt {
+		err := VerifyScript(hex2byte(tt[i].scriptSig), hex2byte(tt[i].scriptPubKey), nil, 0, tt[i].flags)
+		if err != nil && tt[i].expect_err == false {
+			t.Errorf("case #%d error: %v", i+1, err)
+		}
+		if err == nil && tt[i].expect_err == true {
+			t.Errorf("case #%d expected to fail", i+1)
+		}
+	}
+}
+
+func TestVerifyScriptLimits(t *testing.T) {
+	push520 := "4d0802" + strings.Repeat("aa", 520)
+	push521 := "4d0902" + strings.Repeat("aa", 521)
+	tt := []struct {
+		scriptSig    string
+		scriptPubKey string
+		code         ErrorCode
+	}{
+		{"", "51" + strings.Repeat("61", 201), ErrOK},
+		{"", "51" + strings.Repeat("61", 202), ErrOpCount},
+		{"", strings.Repeat(push520+"75", 19) + "51", ErrOK},
+		{"", "51" + strings.Repeat("61", maxScriptSize), ErrScriptSize},
+		{"", push520 + "51", ErrOK},
+		{"", push521 + "51", ErrPushSize},
+		{"", "0063" + push521 + "6851", ErrPushSize},
+		{strings.Repeat("51", 999), "51", ErrOK},
+		{strings.Repeat("51", 1000), "51", ErrStackSize},
+		{strings.Repeat("51", 1001), "51", ErrStackSize},
+		{strings.Repeat("51", 999), "6b51", ErrOK},
+		{strings.Repeat("51", 999), "6b5151", ErrStackSize},
+		{"", "76", ErrInvalidStackOperation},
+		{"", "75", ErrInvalidStackOperation},
+		{"51", "7c", ErrInvalidStackOperation},
+		{"51", "5179", ErrInvalidStackOperation},
+		{"51", "517a", ErrInvalidStackOperation},
+		{"51", "4f79", ErrInvalidStackOperation},
+		{"51", "6c", ErrInvalidAltStackOperation},
+		{"51", "ac", ErrInvalidStackOperation},
+		{"51", "ae", ErrInvalidStackOperation},
+	}
+	for i := range tt {
+		err := VerifyScript(hex2byte(tt[i].scriptSig), hex2byte(tt[i].scriptPubKey), nil, 0, ScriptVerifyNone)
+		if tt[i].code == ErrOK {
+			if err != nil {
+				t.Errorf("case #%d error: %v", i+1, err)
+			}
+			continue
+		}
+		if !errors.Is(err, tt[i].code) {
+			t.Errorf("case #%d expected %s, got %v", i+1, tt[i].code, err)
+		}
+	}
+}
+
+// witness stack size is checked before execution only for tapscript,
+// witness v0 scripts report the first failure of their execution
+func TestWitnessV0StackSize(t *testing.T) {
+	tt := []struct {
+		script []byte
+		code   ErrorCode
+	}{
+		{nil, ErrCleanStack},
+		{[]byte{OP_1}, ErrStackSize},
+		{[]byte{0xba}, ErrBadOpcode},
+	}
+	for i := range tt {
+		witness := make([][]byte, maxStackSize+1)
+		for j := range witness {
+			witness[j] = []byte{}
+		}
+		witness = append(witness, tt[i].script)
+		h := sha256.Sum256(tt[i].script)
+		credit := testCreditingTx(append([]byte{OP_0, 32}, h[:]...), 0)
+		spend := testSpendingTx(nil, witness, credit)
+		prevOuts := PrevOutMap{OutPoint{credit.Hash, 0}: &credit.Out[0]}
+		err := VerifyInput(spend, 0, ScriptVerifyP2SH|ScriptVerifyWitness, prevOuts)
+		if !errors.Is(err, tt[i].code) {
+			t.Errorf("case #%d expected %s, got %v", i+1, tt[i].code, err)
+		}
+	}
+}
+
+func TestVerifyInputArgs(t *testing.T) {
+	h := sha256.Sum256([]byte{OP_1})
+	tx := &Tx{In: []TxIn{{Witness: [][]byte{{OP_1}}}}}
+	prevOuts := PrevOutMap{OutPoint{}: &TxOut{Script: append([]byte{OP_0, 32}, h[:]...)}}
+	flags := ScriptVerifyP2SH | ScriptVerifyWitness
+	tt := []struct {
+		tx       *Tx
+		inIndx   int
+		prevOuts PrevOutFetcher
+		err      error
+	}{
+		{tx, -1, prevOuts, BadInputIndex},
+		{tx, 1, prevOuts, BadInputIndex},
+		{nil, 0, prevOuts, BadInputIndex},
+		{tx, 0, nil, MissingPrevOut},
+		{tx, 0, PrevOutMap{}, MissingPrevOut},
+	}
+	for i := range tt {
+		err := VerifyInput(tt[i].tx, tt[i].inIndx, flags, tt[i].prevOuts)
+		if !errors.Is(err, tt[i].err) {
+			t.Errorf("case #%d expected %v, got %v", i+1, tt[i].err, err)
+		}
+	}
+	err := VerifyTx(tx, flags, nil)
+	if !errors.Is(err, MissingPrevOut) {
+		t.Errorf("expected %v, got %v", MissingPrevOut, err)
+	}
+	err = VerifyTx(nil, flags, prevOuts)
+	if !errors.Is(err, BadInputIndex) {
+		t.Errorf("expected %v, got %v", BadInputIndex, err)
+	}
+	err = VerifyScript(nil, []byte{OP_1}, tx, 5, ScriptVerifyNone)
+	if !errors.Is(err, BadInputIndex) {
+		t.Errorf("expected %v, got %v", BadInputIndex, err)
+	}
+	err = NewEngine(tx, -1, flags, prevOuts).Verify(nil, []byte{OP_1})
+	if !errors.Is(err, BadInputIndex) {
+		t.Errorf("expected %v, got %v", BadInputIndex, err)
+	}
+	err = NewEngine(tx, 1, ScriptVerifyCheckSequenceVerify, nil).Execute([]byte{OP_1, OP_CHECKSEQUENCEVERIFY})
+	if !errors.Is(err, BadInputIndex) {
+		t.Errorf("expected %v, got %v", BadInputIndex, err)
+	}
+	// witness amount is looked up by engine as well
+	err = NewEngine(tx, 0, flags, nil).Verify(nil, prevOuts[OutPoint{}].Script)
+	if !errors.Is(err, MissingPrevOut) {
+		t.Errorf("expected %v, got %v", MissingPrevOut, err)
+	}
+}
+
+func TestVerifyWitnessScriptFlags(t *testing.T) {
+	tt := []struct {
+		witness      []string
+		scriptPubKey string
+		flags        ScriptFlags
+		expect_err   bool
+	}{
+		// OP_IF OP_1 OP_ENDIF
+		{[]string{"02", "635168"}, "", ScriptVerifyWitness, false},
+		{[]string{"02", "635168"}, "", ScriptVerifyWitness | ScriptVerifyMinimalIf, true},
+		{[]string{"01", "635168"}, "", ScriptVerifyWitness | ScriptVerifyMinimalIf, false},
+		// OP_0 <uncompressed key> OP_CHECKSIG OP_NOT
+		{[]string{"0041" + "04" + strings.Repeat("11", 64) + "ac91"}, "", ScriptVerifyWitness, false},
+		{[]string{"0041" + "04" + strings.Repeat("11", 64) + "ac91"}, "", ScriptVerifyWitness | ScriptVerifyWitnessPubKeyType, true},
+		// unknown witness version
+		{[]string{"51"}, "5202abcd", ScriptVerifyWitness, false},
+		{[]string{"51"}, "5202abcd", ScriptVerifyWitness | ScriptVerifyDiscourageUpgradableWitnessProgram, true},
+	}
+	for i := range tt {
+		var witness [][]byte
+		for _, item := range tt[i].witness {
+			witness = append(witness, hex2byte(item))
+		}
+		scriptPubKey := hex2byte(tt[i].scriptPubKey)
+		if len(scriptPubKey) == 0 {
+			h := sha256.Sum256(witness[len(witness)-1])
+			scriptPubKey = append([]byte{OP_0, 0x20}, h[:]...)
+		}
+		tx := &Tx{In: []TxIn{{Witness: witness}}}
+		prevOuts := PrevOutMap{OutPoint{}: &TxOut{Script: scriptPubKey}}
+		err := VerifyInput(tx, 0, ScriptVerifyP2SH|tt[i].flags, prevOuts)
+		if err != nil && tt[i].expect_err == false {
+			t.Errorf("case #%d error: %v", i+1, err)
+		}
+		if err == nil && tt[i].expect_err == true {
+			t.Errorf("case #%d expected to fail", i+1)
+		}
+	}
+}
+
+// cases are taken from bitcoin core src/test/data/tx_valid.json
+func TestVerifyInputWitness(t *testing.T) {
+	tt := []struct {
+		scriptPubKey string
+		amount       uint64
+		tx           string
+	}{
+		{
+			"00144c9c3dfac4207d5d8cb89df5722cb3d712385e3f", 1000,
+			"0100000000010100010000000000000000000000000000000000000000000000000000000000000000000000ffffffff01e8030000000000001976a9144c9c3dfac4207d5d8cb89df5722cb3d712385e3f88ac02483045022100cfb07164b36ba64c1b1e8c7720a56ad64d96f6ef332d3d37f9cb3c96477dc44502200a464cd7a9cf94cd70f66ce4f4f0625ef650052c7afcfe29d7d7e01830ff91ed012103596d3451025c19dbbdeb932d6bf8bfb4ad499b95b6f88db8899efac102e5fc7100000000",
+		},
+		{
+			"0020ff25429251b5a84f452230a3c75fd886b7fc5a7865ce4a7bb7a9d7c5be6da3db", 1000,
+			"0100000000010100010000000000000000000000000000000000000000000000000000000000000000000000ffffffff01e8030000000000001976a9144c9c3dfac4207d5d8cb89df5722cb3d712385e3f88ac02483045022100aa5d8aa40a90f23ce2c3d11bc845ca4a12acd99cbea37de6b9f6d86edebba8cb022022dedc2aa0a255f74d04c0b76ece2d7c691f9dd11a64a8ac49f62a99c3a05f9d01232103596d3451025c19dbbdeb932d6bf8bfb4ad499b95b6f88db8899efac102e5fc71ac00000000",
+		},
+		{
+			"a914fe9c7dacc9fcfbf7e3b7d5ad06aa2b28c5a7b7e387", 1000,
+			"01000000000101000100000000000000000000000000000000000000000000000000000000000000000000171600144c9c3dfac4207d5d8cb89df5722cb3d712385e3fffffffff01e8030000000000001976a9144c9c3dfac4207d5d8cb89df5722cb3d712385e3f88ac02483045022100cfb07164b36ba64c1b1e8c7720a56ad64d96f6ef332d3d37f9cb3c96477dc44502200a464cd7a9cf94cd70f66ce4f4f0625ef650052c7afcfe29d7d7e01830ff91ed012103596d3451025c19dbbdeb932d6bf8bfb4ad499b95b6f88db8899efac102e5fc7100000000",
+		},
+		{
+			"a9142135ab4f0981830311e35600eebc7376dce3a91487", 1000,
+			"0100000000010100010000000000000000000000000000000000000000000000000000000000000000000023220020ff25429251b5a84f452230a3c75fd886b7fc5a7865ce4a7bb7a9d7c5be6da3dbffffffff01e8030000000000001976a9144c9c3dfac4207d5d8cb89df5722cb3d712385e3f88ac02483045022100aa5d8aa40a90f23ce2c3d11bc845ca4a12acd99cbea37de6b9f6d86edebba8cb022022dedc2aa0a255f74d04c0b76ece2d7c691f9dd11a64a8ac49f62a99c3a05f9d01232103596d3451025c19dbbdeb932d6bf8bfb4ad499b95b6f88db8899efac102e5fc71ac00000000",
+		},
+	}
+	flags := ScriptVerifyP2SH | ScriptVerifyWitness
+	for i := range tt {
+		tx := hex2tx(t, tt[i].tx)
+		prevOut := OutPoint{tx.In[0].PrevTx, tx.In[0].PrevTxOutIndx}
+		prevOuts := PrevOutMap{prevOut: &TxOut{tt[i].amount, hex2byte(tt[i].scriptPubKey)}}
+		err := VerifyInput(tx, 0, flags, prevOuts)
+		if err != nil {
+			t.Errorf("case #%d error: %v", i+1, err)
+		}
+		// amount is covered by the signature
+		prevOuts[prevOut].Value++
+		err = VerifyInput(tx, 0, flags, prevOuts)
+		if err == nil {
+			t.Errorf("case #%d expected to fail for modified amount", i+1)
+		}
+		// witness must be checked if witness verification is enabled
+		witness := tx.In[0].Witness
+		tx.In[0].Witness = witness[1:]
+		err = VerifyInput(tx, 0, flags, prevOuts)
+		if err == nil {
+			t.Errorf("case #%d expected to fail for modified witness", i+1)
+		}
+		tx.In[0].Witness = witness
+	}
+}
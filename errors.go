@@ -0,0 +1,211 @@
+//
+// errors.go
+// Copyright (C) 2017 weirdgiraffe <giraffe@cyberzoo.xyz>
+//
+// Distributed under terms of the MIT license.
+//
+
+package bitcoin
+
+import (
+	"fmt"
+)
+
+// ErrorCode identifies the reason of script verification failure. Codes
+// mirror ScriptError_t of bitcoin core
+type ErrorCode int
+
+const (
+	ErrOK ErrorCode = iota
+	ErrUnknown
+	ErrEvalFalse
+	ErrOpReturn
+
+	// max sizes
+	ErrScriptSize
+	ErrPushSize
+	ErrOpCount
+	ErrStackSize
+	ErrSigCount
+	ErrPubKeyCount
+
+	// failed verify operations
+	ErrVerify
+	ErrEqualVerify
+	ErrCheckMultiSigVerify
+	ErrCheckSigVerify
+	ErrNumEqualVerify
+
+	// logical/format/canonical errors
+	ErrBadOpcode
+	ErrDisabledOpcode
+	ErrInvalidStackOperation
+	ErrInvalidAltStackOperation
+	ErrUnbalancedConditional
+
+	// OP_CHECKLOCKTIMEVERIFY and OP_CHECKSEQUENCEVERIFY
+	ErrNegativeLockTime
+	ErrUnsatisfiedLockTime
+
+	// malleability
+	ErrSigHashType
+	ErrSigDER
+	ErrMinimalData
+	ErrSigPushOnly
+	ErrSigHighS
+	ErrSigNullDummy
+	ErrPubKeyType
+	ErrCleanStack
+	ErrMinimalIf
+	ErrSigNullFail
+
+	// softfork safeness
+	ErrDiscourageUpgradableNops
+	ErrDiscourageUpgradableWitnessProgram
+	ErrDiscourageUpgradableTaprootVersion
+	ErrDiscourageOpSuccess
+	ErrDiscourageUpgradablePubKeyType
+
+	// segregated witness
+	ErrWitnessProgramWrongLength
+	ErrWitnessProgramWitnessEmpty
+	ErrWitnessProgramMismatch
+	ErrWitnessMalleated
+	ErrWitnessMalleatedP2SH
+	ErrWitnessUnexpected
+	ErrWitnessPubKeyType
+
+	// taproot
+	ErrSchnorrSigSize
+	ErrSchnorrSigHashType
+	ErrSchnorrSig
+	ErrTaprootWrongControlSize
+	ErrTapscriptValidationWeight
+	ErrTapscriptCheckMultiSig
+	ErrTapscriptMinimalIf
+	ErrTapscriptEmptyPubKey
+
+	// constant scriptCode
+	ErrOpCodeSeparator
+	ErrSigFindAndDelete
+
+	// script number operand overflowed or is not minimally encoded
+	ErrScriptNum
+)
+
+// errorCodeNames are the names of codes used by bitcoin core in test
+// data, without SCRIPT_ERR_ prefix
+var errorCodeNames = map[ErrorCode]string{
+	ErrOK:                                 "OK",
+	ErrUnknown:                            "UNKNOWN_ERROR",
+	ErrEvalFalse:                          "EVAL_FALSE",
+	ErrOpReturn:                           "OP_RETURN",
+	ErrScriptSize:                         "SCRIPT_SIZE",
+	ErrPushSize:                           "PUSH_SIZE",
+	ErrOpCount:                            "OP_COUNT",
+	ErrStackSize:                          "STACK_SIZE",
+	ErrSigCount:                           "SIG_COUNT",
+	ErrPubKeyCount:                        "PUBKEY_COUNT",
+	ErrVerify:                             "VERIFY",
+	ErrEqualVerify:                        "EQUALVERIFY",
+	ErrCheckMultiSigVerify:                "CHECKMULTISIGVERIFY",
+	ErrCheckSigVerify:                     "CHECKSIGVERIFY",
+	ErrNumEqualVerify:                     "NUMEQUALVERIFY",
+	ErrBadOpcode:                          "BAD_OPCODE",
+	ErrDisabledOpcode:                     "DISABLED_OPCODE",
+	ErrInvalidStackOperation:              "INVALID_STACK_OPERATION",
+	ErrInvalidAltStackOperation:           "INVALID_ALTSTACK_OPERATION",
+	ErrUnbalancedConditional:              "UNBALANCED_CONDITIONAL",
+	ErrNegativeLockTime:                   "NEGATIVE_LOCKTIME",
+	ErrUnsatisfiedLockTime:                "UNSATISFIED_LOCKTIME",
+	ErrSigHashType:                        "SIG_HASHTYPE",
+	ErrSigDER:                             "SIG_DER",
+	ErrMinimalData:                        "MINIMALDATA",
+	ErrSigPushOnly:                        "SIG_PUSHONLY",
+	ErrSigHighS:                           "SIG_HIGH_S",
+	ErrSigNullDummy:                       "SIG_NULLDUMMY",
+	ErrPubKeyType:                         "PUBKEYTYPE",
+	ErrCleanStack:                         "CLEANSTACK",
+	ErrMinimalIf:                          "MINIMALIF",
+	ErrSigNullFail:                        "NULLFAIL",
+	ErrDiscourageUpgradableNops:           "DISCOURAGE_UPGRADABLE_NOPS",
+	ErrDiscourageUpgradableWitnessProgram: "DISCOURAGE_UPGRADABLE_WITNESS_PROGRAM",
+	ErrDiscourageUpgradableTaprootVersion: "DISCOURAGE_UPGRADABLE_TAPROOT_VERSION",
+	ErrDiscourageOpSuccess:                "DISCOURAGE_OP_SUCCESS",
+	ErrDiscourageUpgradablePubKeyType:     "DISCOURAGE_UPGRADABLE_PUBKEYTYPE",
+	ErrWitnessProgramWrongLength:          "WITNESS_PROGRAM_WRONG_LENGTH",
+	ErrWitnessProgramWitnessEmpty:         "WITNESS_PROGRAM_WITNESS_EMPTY",
+	ErrWitnessProgramMismatch:             "WITNESS_PROGRAM_MISMATCH",
+	ErrWitnessMalleated:                   "WITNESS_MALLEATED",
+	ErrWitnessMalleatedP2SH:               "WITNESS_MALLEATED_P2SH",
+	ErrWitnessUnexpected:                  "WITNESS_UNEXPECTED",
+	ErrWitnessPubKeyType:                  "WITNESS_PUBKEYTYPE",
+	ErrSchnorrSigSize:                     "SCHNORR_SIG_SIZE",
+	ErrSchnorrSigHashType:                 "SCHNORR_SIG_HASHTYPE",
+	ErrSchnorrSig:                         "SCHNORR_SIG",
+	ErrTaprootWrongControlSize:            "TAPROOT_WRONG_CONTROL_SIZE",
+	ErrTapscriptValidationWeight:          "TAPSCRIPT_VALIDATION_WEIGHT",
+	ErrTapscriptCheckMultiSig:             "TAPSCRIPT_CHECKMULTISIG",
+	ErrTapscriptMinimalIf:                 "TAPSCRIPT_MINIMALIF",
+	ErrTapscriptEmptyPubKey:               "TAPSCRIPT_EMPTY_PUBKEY",
+	ErrOpCodeSeparator:                    "OP_CODESEPARATOR",
+	ErrSigFindAndDelete:                   "SIG_FINDANDDELETE",
+	ErrScriptNum:                          "SCRIPTNUM",
+}
+
+func (c ErrorCode) String() string {
+	if name, ok := errorCodeNames[c]; ok {
+		return name
+	}
+	return fmt.Sprintf("ErrorCode(%d)", int(c))
+}
+
+// Error makes codes usable as targets of errors.Is
+func (c ErrorCode) Error() string {
+	return c.String()
+}
+
+// ScriptError describes why script verification failed
+type ScriptError struct {
+	Code ErrorCode
+	// Offset of the operation in the executed script which caused the
+	// failure or -1 if failure is not caused by a single operation
+	Offset      int
+	Description string
+}
+
+func (e *ScriptError) Error() string {
+	if e.Offset >= 0 {
+		return fmt.Sprintf("%s at offset %d: %s", e.Code, e.Offset, e.Description)
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Description)
+}
+
+// Is return true if target is the code of e or a ScriptError with the
+// same code
+func (e *ScriptError) Is(target error) bool {
+	switch t := target.(type) {
+	case ErrorCode:
+		return e.Code == t
+	case *ScriptError:
+		return e.Code == t.Code
+	}
+	return false
+}
+
+func scriptError(code ErrorCode, format string, args ...interface{}) *ScriptError {
+	return &ScriptError{
+		Code:        code,
+		Offset:      -1,
+		Description: fmt.Sprintf(format, args...),
+	}
+}
+
+// withOffset sets offset of the failed operation for script errors
+// which are not bound to an operation yet
+func withOffset(err error, offset int) error {
+	if e, ok := err.(*ScriptError); ok && e.Offset < 0 {
+		e.Offset = offset
+	}
+	return err
+}
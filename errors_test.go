@@ -0,0 +1,81 @@
+//
+// errors_test.go
+// Copyright (C) 2017 weirdgiraffe <giraffe@cyberzoo.xyz>
+//
+// Distributed under terms of the MIT license.
+//
+
+package bitcoin
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestScriptErrorCodes(t *testing.T) {
+	tt := []struct {
+		scriptSig    string
+		scriptPubKey string
+		flags        ScriptFlags
+		code         ErrorCode
+		offset       int
+	}{
+		{"", "00", ScriptVerifyNone, ErrEvalFalse, -1},
+		{"51", "75", ScriptVerifyNone, ErrEvalFalse, -1},
+		{"51", "0069", ScriptVerifyNone, ErrVerify, 1},
+		{"51", "516a", ScriptVerifyNone, ErrOpReturn, 1},
+		{"5152", "88", ScriptVerifyNone, ErrEqualVerify, 0},
+		{"5152", "9d", ScriptVerifyNone, ErrNumEqualVerify, 0},
+		{"51", "5163", ScriptVerifyNone, ErrUnbalancedConditional, -1},
+		{"51", "5168", ScriptVerifyNone, ErrUnbalancedConditional, 1},
+		{"51", "4c", ScriptVerifyNone, ErrBadOpcode, 0},
+		{"51", "5165", ScriptVerifyNone, ErrBadOpcode, 1},
+		{"0101", "51", ScriptVerifyMinimalData, ErrMinimalData, 0},
+		{"5161", "51", ScriptVerifySigPushOnly, ErrSigPushOnly, -1},
+		{"00", "510000ae", ScriptVerifyNullDummy, ErrSigNullDummy, 3},
+		{"0000", "51010551af", ScriptVerifyNone, ErrCheckMultiSigVerify, 4},
+		{"00", "0000ac", ScriptVerifyNone, ErrEvalFalse, -1},
+		{"00", "0000ad", ScriptVerifyNone, ErrCheckSigVerify, 2},
+		{"51", "5200ae", ScriptVerifyNone, ErrSigCount, 2},
+		{"020100", "8b5287", ScriptVerifyMinimalData, ErrScriptNum, 0},
+		{"5152", "95", ScriptVerifyNone, ErrDisabledOpcode, 0},
+		{"", "00637e6851", ScriptVerifyNone, ErrDisabledOpcode, 2},
+		{"", "5163506851", ScriptVerifyNone, ErrBadOpcode, 2},
+		{"", "0063656851", ScriptVerifyNone, ErrBadOpcode, 2},
+		{"51", "b0", ScriptVerifyDiscourageUpgradableNops, ErrDiscourageUpgradableNops, 0},
+		{"51", "b8", ScriptVerifyDiscourageUpgradableNops, ErrDiscourageUpgradableNops, 0},
+		{"050000008000", "8b", ScriptVerifyNone, ErrScriptNum, 0},
+	}
+	for i := range tt {
+		err := VerifyScript(hex2byte(tt[i].scriptSig), hex2byte(tt[i].scriptPubKey), nil, 0, tt[i].flags)
+		if !errors.Is(err, tt[i].code) {
+			t.Errorf("case #%d expected %s, got %v", i+1, tt[i].code, err)
+			continue
+		}
+		var se *ScriptError
+		if !errors.As(err, &se) {
+			t.Errorf("case #%d expected ScriptError, got %T", i+1, err)
+			continue
+		}
+		if se.Offset != tt[i].offset {
+			t.Errorf("case #%d expected offset %d, got %d", i+1, tt[i].offset, se.Offset)
+		}
+	}
+}
+
+func TestScriptErrorIs(t *testing.T) {
+	err := fmt.Errorf("input 1: %w", scriptError(ErrSigDER, "Signature is not strict DER"))
+	if !errors.Is(err, ErrSigDER) {
+		t.Errorf("expected wrapped error to match its code")
+	}
+	if !errors.Is(err, &ScriptError{Code: ErrSigDER}) {
+		t.Errorf("expected wrapped error to match ScriptError with the same code")
+	}
+	if errors.Is(err, ErrSigHighS) {
+		t.Errorf("expected wrapped error not to match other code")
+	}
+	if ErrSigNullFail.String() != "NULLFAIL" {
+		t.Errorf("unexpected name %q", ErrSigNullFail.String())
+	}
+}
@@ -0,0 +1,135 @@
+//
+// fee.go
+// Copyright (C) 2017 weirdgiraffe <giraffe@cyberzoo.xyz>
+//
+// Distributed under terms of the MIT license.
+//
+
+package bitcoin
+
+import (
+	"fmt"
+)
+
+const (
+	// Coin is the number of satoshis in one bitcoin
+	Coin = 100000000
+	// subsidyHalvingInterval is the number of blocks between subsidy halvings
+	subsidyHalvingInterval = 210000
+)
+
+// OutputValue return the sum of transaction output values. Each value
+// and the sum must be in range of MaxMoney, otherwise *TxError with the
+// index of the output is returned
+func (tx *Tx) OutputValue() (uint64, error) {
+	var total uint64
+	for i := range tx.Out {
+		v := tx.Out[i].Value
+		// values are signed in bitcoin core
+		if int64(v) < 0 {
+			return 0, txError(TxErrVoutNegative, i, "output %d value %d is negative", i, int64(v))
+		}
+		if v > MaxMoney {
+			return 0, txError(TxErrVoutTooLarge, i, "output %d value %d exceeds %d", i, v, uint64(MaxMoney))
+		}
+		total += v
+		if total > MaxMoney {
+			return 0, txError(TxErrTxOutTotalTooLarge, i, "outputs value exceeds %d", uint64(MaxMoney))
+		}
+	}
+	return total, nil
+}
+
+// InputValue return the sum of values of outputs spent by transaction,
+// which are looked up with prevOuts. Coinbase transaction spends nothing.
+// Each value and the sum must be in range of MaxMoney, otherwise
+// *TxError with the index of the input is returned
+func (tx *Tx) InputValue(prevOuts PrevOutFetcher) (uint64, error) {
+	if tx.IsCoinbase() {
+		return 0, nil
+	}
+	if prevOuts == nil {
+		return 0, noPrevOutFetcher()
+	}
+	var total uint64
+	for i := range tx.In {
+		out, err := prevOuts.FetchPrevOut(tx.In[i].PrevTx, tx.In[i].PrevTxOutIndx)
+		if err != nil {
+			return 0, fmt.Errorf("input %d: %w", i, err)
+		}
+		if out.Value > MaxMoney {
+			return 0, txError(TxErrInputValuesOutOfRange, i, "input %d value %d exceeds %d", i, out.Value, uint64(MaxMoney))
+		}
+		total += out.Value
+		if total > MaxMoney {
+			return 0, txError(TxErrInputValuesOutOfRange, i, "inputs value exceeds %d", uint64(MaxMoney))
+		}
+	}
+	return total, nil
+}
+
+// Fee return the difference between input and output values.
+// Coinbase transaction does not pay fee
+func (tx *Tx) Fee(prevOuts PrevOutFetcher) (uint64, error) {
+	if tx.IsCoinbase() {
+		return 0, nil
+	}
+	in, err := tx.InputValue(prevOuts)
+	if err != nil {
+		return 0, err
+	}
+	out, err := tx.OutputValue()
+	if err != nil {
+		return 0, err
+	}
+	if out > in {
+		return 0, fmt.Errorf("Transaction %s spends %d, but outputs %d", tx.Hash, in, out)
+	}
+	return in - out, nil
+}
+
+// FeeRate return transaction fee per virtual byte in satoshis
+func (tx *Tx) FeeRate(prevOuts PrevOutFetcher) (float64, error) {
+	fee, err := tx.Fee(prevOuts)
+	if err != nil {
+		return 0, err
+	}
+	return float64(fee) / float64(tx.VirtualSize()), nil
+}
+
+// BlockSubsidy return the amount of newly created coins which coinbase
+// transaction of block at height may claim
+func BlockSubsidy(height int) uint64 {
+	halvings := uint(height / subsidyHalvingInterval)
+	if halvings >= 64 {
+		return 0
+	}
+	return uint64(50*Coin) >> halvings
+}
+
+// Fees return the sum of fees of all block transactions. Outputs
+// created by earlier transactions of the block are resolved from the
+// block itself, others are looked up with prevOuts
+func (b *Block) Fees(prevOuts PrevOutFetcher) (uint64, error) {
+	blockOuts := NewTxPrevOuts(prevOuts)
+	var fees uint64
+	for i := range b.tx {
+		fee, err := b.tx[i].Fee(blockOuts)
+		if err != nil {
+			return 0, err
+		}
+		fees += fee
+		blockOuts.Add(b.tx[i])
+	}
+	return fees, nil
+}
+
+// Reward return the maximum value coinbase transaction of block at
+// height may claim, which is the block subsidy plus fees
+func (b *Block) Reward(height int, prevOuts PrevOutFetcher) (uint64, error) {
+	fees, err := b.Fees(prevOuts)
+	if err != nil {
+		return 0, err
+	}
+	return BlockSubsidy(height) + fees, nil
+}
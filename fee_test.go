@@ -0,0 +1,145 @@
+//
+// fee_test.go
+// Copyright (C) 2017 weirdgiraffe <giraffe@cyberzoo.xyz>
+//
+// Distributed under terms of the MIT license.
+//
+
+package bitcoin
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestBlockSubsidy(t *testing.T) {
+	tt := []struct {
+		height  int
+		subsidy uint64
+	}{
+		{0, 50 * Coin},
+		{209999, 50 * Coin},
+		{210000, 25 * Coin},
+		{420000, 1250000000},
+		{630000, 625000000},
+		{840000, 312500000},
+		{210000 * 33, 0},
+		{210000 * 64, 0},
+		{210000 * 100, 0},
+	}
+	for i := range tt {
+		if s := BlockSubsidy(tt[i].height); s != tt[i].subsidy {
+			t.Errorf("case #%d: expected subsidy %d, got %d", i, tt[i].subsidy, s)
+		}
+	}
+}
+
+func TestTxFee(t *testing.T) {
+	tx := hex2tx(t, testP2WPKHTx)
+	prevOuts := PrevOutMap{{tx.In[0].PrevTx, tx.In[0].PrevTxOutIndx}: &TxOut{Value: 400000}}
+	if v, err := tx.OutputValue(); err != nil || v != 395019 {
+		t.Errorf("unexpected output value %d: %v", v, err)
+	}
+	in, err := tx.InputValue(prevOuts)
+	if err != nil || in != 400000 {
+		t.Errorf("unexpected input value %d: %v", in, err)
+	}
+	fee, err := tx.Fee(prevOuts)
+	if err != nil || fee != 4981 {
+		t.Errorf("unexpected fee %d: %v", fee, err)
+	}
+	rate, err := tx.FeeRate(prevOuts)
+	if err != nil || rate != 4981.0/109 {
+		t.Errorf("unexpected fee rate %f: %v", rate, err)
+	}
+
+	_, err = tx.Fee(PrevOutMap{})
+	if !errors.Is(err, MissingPrevOut) {
+		t.Errorf("expected MissingPrevOut, got %v", err)
+	}
+	for _, f := range []func(PrevOutFetcher) error{
+		func(p PrevOutFetcher) (err error) { _, err = tx.InputValue(p); return },
+		func(p PrevOutFetcher) (err error) { _, err = tx.Fee(p); return },
+		func(p PrevOutFetcher) (err error) { _, err = tx.FeeRate(p); return },
+	} {
+		if err = f(nil); !errors.Is(err, MissingPrevOut) {
+			t.Errorf("expected MissingPrevOut, got %v", err)
+		}
+	}
+	prevOuts[OutPoint{tx.In[0].PrevTx, tx.In[0].PrevTxOutIndx}].Value = 395018
+	if _, err = tx.Fee(prevOuts); err == nil {
+		t.Errorf("expected error for outputs exceeding inputs")
+	}
+}
+
+func TestTxValueRange(t *testing.T) {
+	prevOuts := PrevOutMap{
+		{DoubleHash{1}, 0}: &TxOut{Value: MaxMoney},
+		{DoubleHash{1}, 1}: &TxOut{Value: MaxMoney},
+		{DoubleHash{1}, 2}: &TxOut{Value: 1 << 63},
+	}
+	tt := []struct {
+		in         []uint32
+		out        []uint64
+		expect_err error
+	}{
+		{[]uint32{0}, []uint64{MaxMoney}, nil},
+		{[]uint32{0, 1}, []uint64{1}, TxErrInputValuesOutOfRange},
+		{[]uint32{2}, []uint64{1}, TxErrInputValuesOutOfRange},
+		{[]uint32{0}, []uint64{MaxMoney, 1}, TxErrTxOutTotalTooLarge},
+		// the sum of outputs wraps around to 0
+		{[]uint32{0}, []uint64{1 << 63, 1 << 63}, TxErrVoutNegative},
+		{[]uint32{0}, []uint64{MaxMoney + 1}, TxErrVoutTooLarge},
+	}
+	for i := range tt {
+		tx := &Tx{}
+		for _, indx := range tt[i].in {
+			tx.In = append(tx.In, TxIn{PrevTx: DoubleHash{1}, PrevTxOutIndx: indx})
+		}
+		for _, v := range tt[i].out {
+			tx.Out = append(tx.Out, TxOut{Value: v})
+		}
+		_, err := tx.Fee(prevOuts)
+		if tt[i].expect_err == nil {
+			if err != nil {
+				t.Errorf("case #%d error: %v", i+1, err)
+			}
+			continue
+		}
+		if !errors.Is(err, tt[i].expect_err) {
+			t.Errorf("case #%d expected %s, got %v", i+1, tt[i].expect_err, err)
+		}
+	}
+}
+
+func TestBlockReward(t *testing.T) {
+	coinbase := &Tx{
+		In:  []TxIn{{PrevTxOutIndx: 0xffffffff, Script: []byte{0x01, 0x01}}},
+		Out: []TxOut{{Value: 50*Coin + 300}},
+	}
+	coinbase.Hash.Update(coinbase.Raw())
+	// tx1 spends an output of earlier block, tx2 spends output of tx1
+	tx1 := &Tx{
+		In:  []TxIn{{PrevTx: DoubleHash{1}}},
+		Out: []TxOut{{Value: 900}},
+	}
+	tx1.Hash.Update(tx1.Raw())
+	tx2 := &Tx{
+		In:  []TxIn{{PrevTx: tx1.Hash}},
+		Out: []TxOut{{Value: 800}},
+	}
+	tx2.Hash.Update(tx2.Raw())
+	b := &Block{tx: []*Tx{coinbase, tx1, tx2}}
+	prevOuts := PrevOutMap{{DoubleHash{1}, 0}: &TxOut{Value: 1100}}
+	fees, err := b.Fees(prevOuts)
+	if err != nil || fees != 300 {
+		t.Errorf("unexpected fees %d: %v", fees, err)
+	}
+	reward, err := b.Reward(1, prevOuts)
+	if err != nil || reward != coinbase.Out[0].Value {
+		t.Errorf("unexpected reward %d: %v", reward, err)
+	}
+	if _, err = b.Fees(PrevOutMap{}); !errors.Is(err, MissingPrevOut) {
+		t.Errorf("expected MissingPrevOut, got %v", err)
+	}
+}
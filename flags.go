@@ -0,0 +1,151 @@
+//
+// flags.go
+// Copyright (C) 2017 weirdgiraffe <giraffe@cyberzoo.xyz>
+//
+// Distributed under terms of the MIT license.
+//
+
+package bitcoin
+
+// ScriptFlags is a bit set of script verification rules to apply. Bits
+// are the same as SCRIPT_VERIFY_* flags of bitcoin core
+type ScriptFlags uint32
+
+const (
+	ScriptVerifyNone ScriptFlags = 0
+	// ScriptVerifyP2SH enables evaluation of pay-to-script-hash
+	// redeem scripts (BIP16)
+	ScriptVerifyP2SH ScriptFlags = 1 << 0
+	// ScriptVerifyStrictEncoding requires signatures to be strict DER
+	// with defined sighash type and public keys to be either compressed
+	// or uncompressed
+	ScriptVerifyStrictEncoding ScriptFlags = 1 << 1
+	// ScriptVerifyDERSignatures requires signatures to be strict DER
+	// (BIP66)
+	ScriptVerifyDERSignatures ScriptFlags = 1 << 2
+	// ScriptVerifyLowS requires S value of signatures to be at most
+	// half of the curve order
+	ScriptVerifyLowS ScriptFlags = 1 << 3
+	// ScriptVerifyNullDummy requires the extra OP_CHECKMULTISIG stack
+	// element to be empty (BIP147)
+	ScriptVerifyNullDummy ScriptFlags = 1 << 4
+	// ScriptVerifySigPushOnly requires scriptSig to contain only push
+	// operations
+	ScriptVerifySigPushOnly ScriptFlags = 1 << 5
+	// ScriptVerifyMinimalData requires data to be pushed with the
+	// smallest possible push operation
+	ScriptVerifyMinimalData ScriptFlags = 1 << 6
+	// ScriptVerifyDiscourageUpgradableNops makes reserved OP_NOPx
+	// operations fail
+	ScriptVerifyDiscourageUpgradableNops ScriptFlags = 1 << 7
+	// ScriptVerifyCleanStack requires exactly one element to be left on
+	// the stack after evaluation. Should be used together with
+	// ScriptVerifyP2SH and ScriptVerifyWitness
+	ScriptVerifyCleanStack ScriptFlags = 1 << 8
+	// ScriptVerifyCheckLockTimeVerify enables OP_CHECKLOCKTIMEVERIFY,
+	// which is OP_NOP2 otherwise (BIP65)
+	ScriptVerifyCheckLockTimeVerify ScriptFlags = 1 << 9
+	// ScriptVerifyCheckSequenceVerify enables OP_CHECKSEQUENCEVERIFY,
+	// which is OP_NOP3 otherwise (BIP112)
+	ScriptVerifyCheckSequenceVerify ScriptFlags = 1 << 10
+	// ScriptVerifyWitness enables verification of segregated witness
+	// programs (BIP141). Should be used together with ScriptVerifyP2SH
+	ScriptVerifyWitness ScriptFlags = 1 << 11
+	// ScriptVerifyDiscourageUpgradableWitnessProgram makes witness
+	// programs of unknown versions fail
+	ScriptVerifyDiscourageUpgradableWitnessProgram ScriptFlags = 1 << 12
+	// ScriptVerifyMinimalIf requires argument of OP_IF and OP_NOTIF in
+	// witness v0 scripts to be either empty or 1
+	ScriptVerifyMinimalIf ScriptFlags = 1 << 13
+	// ScriptVerifyNullFail requires signatures of failed signature
+	// checks to be empty
+	ScriptVerifyNullFail ScriptFlags = 1 << 14
+	// ScriptVerifyWitnessPubKeyType requires public keys in witness v0
+	// scripts to be compressed
+	ScriptVerifyWitnessPubKeyType ScriptFlags = 1 << 15
+	// ScriptVerifyConstScriptCode makes OP_CODESEPARATOR and signatures
+	// found in the script code fail in non-witness scripts
+	ScriptVerifyConstScriptCode ScriptFlags = 1 << 16
+	// ScriptVerifyTaproot enables verification of witness v1 taproot
+	// programs (BIP341, BIP342). Should be used together with
+	// ScriptVerifyWitness
+	ScriptVerifyTaproot ScriptFlags = 1 << 17
+	// ScriptVerifyDiscourageUpgradableTaprootVersion makes taproot
+	// script leaves of unknown versions fail
+	ScriptVerifyDiscourageUpgradableTaprootVersion ScriptFlags = 1 << 18
+	// ScriptVerifyDiscourageOpSuccess makes tapscripts containing
+	// OP_SUCCESSx fail
+	ScriptVerifyDiscourageOpSuccess ScriptFlags = 1 << 19
+	// ScriptVerifyDiscourageUpgradablePubKeyType makes signature checks
+	// with public keys of unknown types fail in tapscript
+	ScriptVerifyDiscourageUpgradablePubKeyType ScriptFlags = 1 << 20
+)
+
+// MandatoryScriptFlags are the rules that every transaction in a new
+// block has to satisfy
+const MandatoryScriptFlags = ScriptVerifyP2SH |
+	ScriptVerifyDERSignatures |
+	ScriptVerifyNullDummy |
+	ScriptVerifyCheckLockTimeVerify |
+	ScriptVerifyCheckSequenceVerify |
+	ScriptVerifyWitness |
+	ScriptVerifyTaproot
+
+// StandardScriptFlags are the rules bitcoin core applies to unconfirmed
+// transactions before relaying them
+const StandardScriptFlags = MandatoryScriptFlags |
+	ScriptVerifyStrictEncoding |
+	ScriptVerifyMinimalData |
+	ScriptVerifyDiscourageUpgradableNops |
+	ScriptVerifyCleanStack |
+	ScriptVerifyMinimalIf |
+	ScriptVerifyNullFail |
+	ScriptVerifyLowS |
+	ScriptVerifyDiscourageUpgradableWitnessProgram |
+	ScriptVerifyWitnessPubKeyType |
+	ScriptVerifyConstScriptCode |
+	ScriptVerifyDiscourageUpgradableTaprootVersion |
+	ScriptVerifyDiscourageOpSuccess |
+	ScriptVerifyDiscourageUpgradablePubKeyType
+
+// mainnet heights of the blocks where soft forks were activated
+const (
+	bip66Height  = 363725
+	bip65Height  = 388381
+	csvHeight    = 419328
+	segwitHeight = 481824
+)
+
+// scriptFlagExceptions are the mainnet blocks which violate rules that
+// are applied to all other blocks
+var scriptFlagExceptions = map[DoubleHash]ScriptFlags{
+	// the only block violating BIP16
+	mustParseHash("00000000000002dc756eebf4f49723ed8d30cc28a5f108eb94b1ba88ac4f9c22"): ScriptVerifyNone,
+	// the only block violating taproot rules
+	mustParseHash("0000000000000000000f14c35b2d841e986ab5441de8c585d5ffe55ea1e395ad"): ScriptVerifyP2SH | ScriptVerifyWitness,
+}
+
+// ConsensusScriptFlags return rules which transactions of mainnet block
+// at height with hash blockHash have to satisfy
+//
+// P2SH, segwit and taproot rules are applied to all blocks except the
+// two historical blocks, because no earlier block violated them
+func ConsensusScriptFlags(height int, blockHash DoubleHash) ScriptFlags {
+	flags := ScriptVerifyP2SH | ScriptVerifyWitness | ScriptVerifyTaproot
+	if f, ok := scriptFlagExceptions[blockHash]; ok {
+		flags = f
+	}
+	if height >= bip66Height {
+		flags |= ScriptVerifyDERSignatures
+	}
+	if height >= bip65Height {
+		flags |= ScriptVerifyCheckLockTimeVerify
+	}
+	if height >= csvHeight {
+		flags |= ScriptVerifyCheckSequenceVerify
+	}
+	if height >= segwitHeight {
+		flags |= ScriptVerifyNullDummy
+	}
+	return flags
+}
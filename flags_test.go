@@ -0,0 +1,42 @@
+//
+// flags_test.go
+// Copyright (C) 2017 weirdgiraffe <giraffe@cyberzoo.xyz>
+//
+// Distributed under terms of the MIT license.
+//
+
+package bitcoin
+
+import (
+	"testing"
+)
+
+func TestConsensusScriptFlags(t *testing.T) {
+	base := ScriptVerifyP2SH | ScriptVerifyWitness | ScriptVerifyTaproot
+	tt := []struct {
+		height int
+		hash   string
+		flags  ScriptFlags
+	}{
+		{0, "000000000019d6689c085ae165831e934ff763ae46a2a6c172b3f1b60a8ce26f", base},
+		{170060, "00000000000002dc756eebf4f49723ed8d30cc28a5f108eb94b1ba88ac4f9c22", ScriptVerifyNone},
+		{363724, "0000000000000000000000000000000000000000000000000000000000000001", base},
+		{363725, "0000000000000000000000000000000000000000000000000000000000000001", base | ScriptVerifyDERSignatures},
+		{388381, "0000000000000000000000000000000000000000000000000000000000000001", base | ScriptVerifyDERSignatures | ScriptVerifyCheckLockTimeVerify},
+		{419328, "0000000000000000000000000000000000000000000000000000000000000001", base | ScriptVerifyDERSignatures | ScriptVerifyCheckLockTimeVerify | ScriptVerifyCheckSequenceVerify},
+		{481824, "0000000000000000000000000000000000000000000000000000000000000001", MandatoryScriptFlags},
+		{
+			692261, "0000000000000000000f14c35b2d841e986ab5441de8c585d5ffe55ea1e395ad",
+			MandatoryScriptFlags &^ ScriptVerifyTaproot,
+		},
+	}
+	for i := range tt {
+		flags := ConsensusScriptFlags(tt[i].height, mustParseHash(tt[i].hash))
+		if flags != tt[i].flags {
+			t.Errorf("case #%d expected %#x, got %#x", i+1, tt[i].flags, flags)
+		}
+	}
+	if StandardScriptFlags&MandatoryScriptFlags != MandatoryScriptFlags {
+		t.Errorf("standard flags must include mandatory flags")
+	}
+}
@@ -0,0 +1,211 @@
+//
+// locktime.go
+// Copyright (C) 2017 weirdgiraffe <giraffe@cyberzoo.xyz>
+//
+// Distributed under terms of the MIT license.
+//
+
+package bitcoin
+
+import (
+	"fmt"
+)
+
+const (
+	// LockTimeThreshold separates lock time interpreted as block height
+	// (below) from lock time interpreted as unix timestamp
+	LockTimeThreshold = 500000000
+
+	// SequenceFinal is the sequence number that disables lock time of
+	// the input
+	SequenceFinal = 0xffffffff
+	// SequenceLockTimeDisableFlag disables relative lock time of the
+	// input (BIP68)
+	SequenceLockTimeDisableFlag = 1 << 31
+	// SequenceLockTimeTypeFlag defines that relative lock time is
+	// measured in units of 512 seconds instead of blocks
+	SequenceLockTimeTypeFlag = 1 << 22
+	// SequenceLockTimeMask extracts relative lock time from sequence
+	SequenceLockTimeMask = 0x0000ffff
+	// SequenceLockTimeGranularity is log2 of relative lock time unit
+	// in seconds
+	SequenceLockTimeGranularity = 9
+)
+
+// lockTimeArg decodes argument of OP_CHECKLOCKTIMEVERIFY and
+// OP_CHECKSEQUENCEVERIFY from the top of the stack
+func (e *Engine) lockTimeArg(op byte) (int64, error) {
+	b, err := e.main.Top()
+	if err != nil {
+		return 0, err
+	}
+	// lock time arguments are allowed to be 5 bytes long to cover the
+	// whole range of uint32
+	n, err := e.parseScriptInt(b, LockTimeScriptIntSize)
+	if err != nil {
+		return 0, err
+	}
+	if n.Int64() < 0 {
+		return 0, scriptError(ErrNegativeLockTime, "%s: negative lock time", OpcodeName(op))
+	}
+	return n.Int64(), nil
+}
+
+// opCheckLockTimeVerify implements OP_CHECKLOCKTIMEVERIFY, which fails
+// the script unless transaction lock time is at least the top stack
+// item. The item is left on the stack
+// check https://github.com/bitcoin/bips/blob/master/bip-0065.mediawiki
+func (e *Engine) opCheckLockTimeVerify() error {
+	lockTime, err := e.lockTimeArg(OP_CHECKLOCKTIMEVERIFY)
+	if err != nil {
+		return err
+	}
+	if e.tx == nil {
+		return scriptError(ErrUnsatisfiedLockTime, "OP_CHECKLOCKTIMEVERIFY: unsatisfied lock time")
+	}
+	txLockTime := int64(e.tx.LockTime)
+	// both lock times must be either heights or timestamps
+	if (txLockTime < LockTimeThreshold) != (lockTime < LockTimeThreshold) {
+		return scriptError(ErrUnsatisfiedLockTime, "OP_CHECKLOCKTIMEVERIFY: lock time type mismatch")
+	}
+	if lockTime > txLockTime {
+		return scriptError(ErrUnsatisfiedLockTime, "OP_CHECKLOCKTIMEVERIFY: unsatisfied lock time")
+	}
+	// final input disables transaction lock time, so it could be
+	// bypassed otherwise
+	if e.tx.In[e.inIndx].SequenceNum == SequenceFinal {
+		return scriptError(ErrUnsatisfiedLockTime, "OP_CHECKLOCKTIMEVERIFY: input sequence is final")
+	}
+	return nil
+}
+
+// opCheckSequenceVerify implements OP_CHECKSEQUENCEVERIFY, which fails
+// the script unless relative lock time of the input is at least the top
+// stack item. The item is left on the stack
+// check https://github.com/bitcoin/bips/blob/master/bip-0112.mediawiki
+func (e *Engine) opCheckSequenceVerify() error {
+	sequence, err := e.lockTimeArg(OP_CHECKSEQUENCEVERIFY)
+	if err != nil {
+		return err
+	}
+	// argument with disable flag is reserved for future soft forks
+	if sequence&SequenceLockTimeDisableFlag != 0 {
+		return nil
+	}
+	if e.tx == nil || e.tx.Version < 2 {
+		return scriptError(ErrUnsatisfiedLockTime, "OP_CHECKSEQUENCEVERIFY: transaction version is less than 2")
+	}
+	txSequence := int64(e.tx.In[e.inIndx].SequenceNum)
+	if txSequence&SequenceLockTimeDisableFlag != 0 {
+		return scriptError(ErrUnsatisfiedLockTime, "OP_CHECKSEQUENCEVERIFY: input relative lock time is disabled")
+	}
+	mask := int64(SequenceLockTimeTypeFlag | SequenceLockTimeMask)
+	sequence &= mask
+	txSequence &= mask
+	// both lock times must be either in blocks or in time units
+	if (txSequence < SequenceLockTimeTypeFlag) != (sequence < SequenceLockTimeTypeFlag) {
+		return scriptError(ErrUnsatisfiedLockTime, "OP_CHECKSEQUENCEVERIFY: lock time type mismatch")
+	}
+	if sequence > txSequence {
+		return scriptError(ErrUnsatisfiedLockTime, "OP_CHECKSEQUENCEVERIFY: unsatisfied lock time")
+	}
+	return nil
+}
+
+// IsFinalTx return true if transaction lock time allows to include it
+// into block at height with time blockTime. Since BIP113 blockTime is
+// the median time past of the previous block
+func IsFinalTx(tx *Tx, height int, blockTime int64) bool {
+	return CheckFinalTx(tx, height, blockTime) == nil
+}
+
+// CheckFinalTx is the same as IsFinalTx, but return *TxError with the
+// index of the first input which is not final if transaction is locked
+func CheckFinalTx(tx *Tx, height int, blockTime int64) error {
+	if tx.LockTime == 0 {
+		return nil
+	}
+	limit := int64(height)
+	if tx.LockTime >= LockTimeThreshold {
+		limit = blockTime
+	}
+	if int64(tx.LockTime) < limit {
+		return nil
+	}
+	// lock time is ignored when all inputs are final
+	for i := range tx.In {
+		if tx.In[i].SequenceNum != SequenceFinal {
+			return txError(TxErrNonFinal, i,
+				"input %d is locked until lock time %d", i, tx.LockTime)
+		}
+	}
+	return nil
+}
+
+// PrevOutPosition is the position in the chain of output spent by
+// transaction input, which relative lock time is counted from
+type PrevOutPosition struct {
+	// Height of block which includes the output
+	Height int
+	// MedianTimePast of the block preceding that block
+	MedianTimePast int64
+}
+
+// SequenceLock is the result of relative lock time calculation (BIP68).
+// MinHeight and MinTime are the last block height and previous block
+// median time past at which transaction is still locked, or -1 if there
+// is no lock. HeightInput and TimeInput are the inputs which set them
+type SequenceLock struct {
+	MinHeight   int
+	MinTime     int64
+	HeightInput int
+	TimeInput   int
+}
+
+// CalculateSequenceLocks computes relative lock times of tx inputs,
+// prevOuts are the positions of outputs spent by each input. BIP68 is
+// enforced for transactions of version 2 and higher when flags include
+// ScriptVerifyCheckSequenceVerify, which activated together with it
+func CalculateSequenceLocks(tx *Tx, prevOuts []PrevOutPosition, flags ScriptFlags) (*SequenceLock, error) {
+	if len(prevOuts) != len(tx.In) {
+		return nil, fmt.Errorf("Got %d previous output positions for %d inputs", len(prevOuts), len(tx.In))
+	}
+	l := &SequenceLock{MinHeight: -1, MinTime: -1, HeightInput: -1, TimeInput: -1}
+	if tx.Version < 2 || flags&ScriptVerifyCheckSequenceVerify == 0 {
+		return l, nil
+	}
+	for i := range tx.In {
+		sequence := tx.In[i].SequenceNum
+		if sequence&SequenceLockTimeDisableFlag != 0 {
+			continue
+		}
+		value := int64(sequence & SequenceLockTimeMask)
+		if sequence&SequenceLockTimeTypeFlag != 0 {
+			minTime := prevOuts[i].MedianTimePast + value<<SequenceLockTimeGranularity - 1
+			if minTime > l.MinTime {
+				l.MinTime, l.TimeInput = minTime, i
+			}
+		} else {
+			minHeight := prevOuts[i].Height + int(value) - 1
+			if minHeight > l.MinHeight {
+				l.MinHeight, l.HeightInput = minHeight, i
+			}
+		}
+	}
+	return l, nil
+}
+
+// Check return nil if transaction may be included into block at height,
+// which previous block has median time past medianTimePast. Otherwise
+// *TxError with the index of locked input is returned
+func (l *SequenceLock) Check(height int, medianTimePast int64) error {
+	if l.MinHeight >= height {
+		return txError(TxErrNonBIP68Final, l.HeightInput,
+			"input %d is locked until height %d", l.HeightInput, l.MinHeight+1)
+	}
+	if l.MinTime >= medianTimePast {
+		return txError(TxErrNonBIP68Final, l.TimeInput,
+			"input %d is locked until median time past %d", l.TimeInput, l.MinTime+1)
+	}
+	return nil
+}
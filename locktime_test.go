@@ -0,0 +1,209 @@
+//
+// locktime_test.go
+// Copyright (C) 2017 weirdgiraffe <giraffe@cyberzoo.xyz>
+//
+// Distributed under terms of the MIT license.
+//
+
+package bitcoin
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckLockTimeVerify(t *testing.T) {
+	tt := []struct {
+		arg        string
+		lockTime   uint32
+		sequence   uint32
+		expect_err bool
+	}{
+		{"", 0, 0, false},
+		{"64", 100, 0, false},
+		{"64", 99, 0, true},
+		{"64", 101, 0xfffffffe, false},
+		// final input could bypass lock time
+		{"64", 100, 0xffffffff, true},
+		// 500000000 is a timestamp
+		{"0065cd1d", 500000000, 0, false},
+		{"0065cd1d", 499999999, 0, true},
+		{"64", 500000000, 0, true},
+		// 5 byte argument
+		{"ffffffff00", 0xffffffff, 0, false},
+		{"ffffffff0000", 0xffffffff, 0, true},
+		// negative lock time
+		{"81", 100, 0, true},
+		{"ffffffff", 100, 0, true},
+		// negative zero is zero
+		{"0080", 100, 0, false},
+	}
+	for i := range tt {
+		tx := &Tx{
+			In:       []TxIn{{SequenceNum: tt[i].sequence}},
+			LockTime: tt[i].lockTime,
+		}
+		script := append(pushData(hex2byte(tt[i].arg)), OP_CHECKLOCKTIMEVERIFY, OP_DROP, OP_1)
+		err := VerifyScript(nil, script, tx, 0, ScriptVerifyCheckLockTimeVerify)
+		if err != nil && tt[i].expect_err == false {
+			t.Errorf("case #%d error: %v", i+1, err)
+		}
+		if err == nil && tt[i].expect_err == true {
+			t.Errorf("case #%d expected to fail", i+1)
+		}
+		// without the flag the operation is OP_NOP2
+		err = VerifyScript(nil, script, tx, 0, ScriptVerifyNone)
+		if err != nil {
+			t.Errorf("case #%d error without flag: %v", i+1, err)
+		}
+	}
+}
+
+func TestCheckSequenceVerify(t *testing.T) {
+	tt := []struct {
+		arg        string
+		version    uint32
+		sequence   uint32
+		expect_err bool
+	}{
+		{"", 2, 0, false},
+		{"0a", 2, 10, false},
+		{"0a", 2, 9, true},
+		{"0a", 1, 10, true},
+		// only masked bits are compared
+		{"0a", 2, 0x003f000a, false},
+		// relative lock time in 512 seconds units
+		{"0a0040", 2, 0x0040000a, false},
+		{"0a0040", 2, 0x00400009, true},
+		{"0a0040", 2, 10, true},
+		{"0a", 2, 0x0040000a, true},
+		// input relative lock time is disabled
+		{"0a", 2, 0x8000000a, true},
+		// disabled argument is a NOP
+		{"0000008000", 1, 0xffffffff, false},
+		{"81", 2, 10, true},
+	}
+	for i := range tt {
+		tx := &Tx{
+			Version: tt[i].version,
+			In:      []TxIn{{SequenceNum: tt[i].sequence}},
+		}
+		script := append(pushData(hex2byte(tt[i].arg)), OP_CHECKSEQUENCEVERIFY, OP_DROP, OP_1)
+		err := VerifyScript(nil, script, tx, 0, ScriptVerifyCheckSequenceVerify)
+		if err != nil && tt[i].expect_err == false {
+			t.Errorf("case #%d error: %v", i+1, err)
+		}
+		if err == nil && tt[i].expect_err == true {
+			t.Errorf("case #%d expected to fail", i+1)
+		}
+		// without the flag the operation is OP_NOP3
+		err = VerifyScript(nil, script, tx, 0, ScriptVerifyNone)
+		if err != nil {
+			t.Errorf("case #%d error without flag: %v", i+1, err)
+		}
+	}
+}
+
+func TestIsFinalTx(t *testing.T) {
+	tt := []struct {
+		lockTime  uint32
+		sequence  uint32
+		height    int
+		blockTime int64
+		final     bool
+	}{
+		{0, 0, 0, 0, true},
+		{100, 0, 101, 0, true},
+		{100, 0, 100, 0, false},
+		{100, 0, 99, 0, false},
+		// all inputs are final
+		{100, SequenceFinal, 100, 0, true},
+		{100, SequenceFinal - 1, 100, 0, false},
+		// lock time is a timestamp
+		{500000000, 0, 1000000000, 500000001, true},
+		{500000000, 0, 1000000000, 500000000, false},
+		{LockTimeThreshold - 1, 0, LockTimeThreshold, 0, true},
+	}
+	for i := range tt {
+		tx := &Tx{
+			In: []TxIn{
+				{SequenceNum: SequenceFinal},
+				{SequenceNum: tt[i].sequence},
+			},
+			LockTime: tt[i].lockTime,
+		}
+		if IsFinalTx(tx, tt[i].height, tt[i].blockTime) != tt[i].final {
+			t.Errorf("case #%d: expected final %v", i, tt[i].final)
+		}
+		err := CheckFinalTx(tx, tt[i].height, tt[i].blockTime)
+		if tt[i].final {
+			if err != nil {
+				t.Errorf("case #%d error: %v", i, err)
+			}
+			continue
+		}
+		var te *TxError
+		if !errors.As(err, &te) || te.Code != TxErrNonFinal || te.Index != 1 {
+			t.Errorf("case #%d expected %s of input 1, got %v", i, TxErrNonFinal, err)
+		}
+	}
+}
+
+func TestSequenceLocks(t *testing.T) {
+	flags := ScriptVerifyCheckSequenceVerify
+	prevOuts := []PrevOutPosition{{100, 1000000}, {200, 2000000}}
+	tt := []struct {
+		version    uint32
+		sequence   [2]uint32
+		flags      ScriptFlags
+		height     int
+		mtp        int64
+		expect_err bool
+		input      int
+	}{
+		// no relative lock time
+		{2, [2]uint32{SequenceFinal, SequenceFinal}, flags, 0, 0, false, -1},
+		{1, [2]uint32{10, 10}, flags, 0, 0, false, -1},
+		{2, [2]uint32{10, 10}, ScriptVerifyNone, 0, 0, false, -1},
+		// height locks, the second input is locked longer
+		{2, [2]uint32{10, 10}, flags, 210, 0, false, -1},
+		{2, [2]uint32{10, 10}, flags, 209, 0, true, 1},
+		{2, [2]uint32{200, 10}, flags, 300, 0, false, -1},
+		{2, [2]uint32{200, 10}, flags, 299, 0, true, 0},
+		{2, [2]uint32{10, SequenceLockTimeDisableFlag | 1000}, flags, 110, 0, false, -1},
+		// time locks in units of 512 seconds
+		{2, [2]uint32{SequenceLockTimeTypeFlag | 2, SequenceFinal}, flags, 0, 1000000 + 1024, false, -1},
+		{2, [2]uint32{SequenceLockTimeTypeFlag | 2, SequenceFinal}, flags, 0, 1000000 + 1023, true, 0},
+		// both locks
+		{2, [2]uint32{SequenceLockTimeTypeFlag | 2, 5}, flags, 205, 1001024, false, -1},
+		{2, [2]uint32{SequenceLockTimeTypeFlag | 2, 5}, flags, 204, 1001024, true, 1},
+		{2, [2]uint32{SequenceLockTimeTypeFlag | 2, 5}, flags, 205, 1001023, true, 0},
+	}
+	for i := range tt {
+		tx := &Tx{
+			Version: tt[i].version,
+			In: []TxIn{
+				{SequenceNum: tt[i].sequence[0]},
+				{SequenceNum: tt[i].sequence[1]},
+			},
+		}
+		l, err := CalculateSequenceLocks(tx, prevOuts, tt[i].flags)
+		if err != nil {
+			t.Fatalf("case #%d: unexpected error: %v", i, err)
+		}
+		err = l.Check(tt[i].height, tt[i].mtp)
+		if !tt[i].expect_err {
+			if err != nil {
+				t.Errorf("case #%d: unexpected error: %v", i, err)
+			}
+			continue
+		}
+		var te *TxError
+		if !errors.As(err, &te) || te.Code != TxErrNonBIP68Final || te.Index != tt[i].input {
+			t.Errorf("case #%d: expected input %d to be locked, got %v", i, tt[i].input, err)
+		}
+	}
+	if _, err := CalculateSequenceLocks(&Tx{In: make([]TxIn, 3)}, prevOuts, flags); err == nil {
+		t.Errorf("expected error for missing previous output position")
+	}
+}
@@ -11,389 +11,428 @@ import (
 	"bytes"
 	"crypto/sha1"
 	"crypto/sha256"
-	"encoding/binary"
-	"errors"
 	"fmt"
+	"strings"
 
 	"golang.org/x/crypto/ripemd160"
 )
 
-var InvalidTransaction = errors.New("Transacrion is invalid")
-
 // OpConstants implements all script operations that are constants
 // check https://en.bitcoin.it/wiki/Script#Constants
 //
 // return number of consumed bytes from script
-func OpConstants(op byte, script []byte, s *stack) (n int, err error) {
+func OpConstants(op byte, script []byte, s *Stack) (n int, err error) {
 	switch {
-	case op == OP_0:
-		s.PushSlice([]byte{})
-	case op < OP_PUSHDATA1:
-		n = int(op)
-		s.PushSlice(script[:n])
-	case op == OP_PUSHDATA1:
-		n = int(script[0]) + 1
-		s.PushSlice(script[1:n])
-	case op == OP_PUSHDATA2:
-		n = int(binary.LittleEndian.Uint16(script)) + 2
-		s.PushSlice(script[2:n])
-	case op == OP_PUSHDATA4:
-		n = int(binary.LittleEndian.Uint32(script)) + 4
-		s.PushSlice(script[4:n])
+	case op <= OP_PUSHDATA4:
+		var ok bool
+		n, ok = pushDataLen(op, script)
+		if !ok {
+			return 0, scriptError(ErrBadOpcode, "%s is truncated", OpcodeName(op))
+		}
+		s.PushSlice(script[pushDataPrefixLen(op):n])
 	case op == OP_1NEGATE:
 		s.PushByte(0x81)
 	case OP_1 <= op && op <= OP_16:
 		b := op - OP_1 + 1
 		s.PushByte(b)
 	default:
-		err = fmt.Errorf("0x%02x not a Script Constants op", op)
+		err = scriptError(ErrBadOpcode, "0x%02x not a Script Constants op", op)
 	}
 	return
 }
 
+// OpFlowControl implements all script operations that are Flow control
+// check https://en.bitcoin.it/wiki/Script#Flow_control
+//
+// cond keeps the state of nested conditional branches and is also
+// updated for branches that are not executed
+func OpFlowControl(op byte, main *Stack, cond *condStack) error {
+	switch op {
+	case OP_IF, OP_NOTIF:
+		v := false
+		if cond.AllTrue() {
+			b, err := main.Pop()
+			if err != nil {
+				return scriptError(ErrInvalidStackOperation, "%s without argument", OpcodeName(op))
+			}
+			v = slice2bool(b)
+			if op == OP_NOTIF {
+				v = !v
+			}
+		}
+		cond.Push(v)
+	case OP_ELSE:
+		if cond.Empty() {
+			return scriptError(ErrUnbalancedConditional, "%s without OP_IF", OpcodeName(op))
+		}
+		cond.Toggle()
+	case OP_ENDIF:
+		if cond.Empty() {
+			return scriptError(ErrUnbalancedConditional, "%s without OP_IF", OpcodeName(op))
+		}
+		cond.Pop()
+	case OP_VERIFY:
+		b, err := main.Pop()
+		if err != nil {
+			return err
+		}
+		if !slice2bool(b) {
+			return scriptError(ErrVerify, "OP_VERIFY failed")
+		}
+	case OP_RETURN:
+		return scriptError(ErrOpReturn, "OP_RETURN is executed")
+	case OP_VERIF, OP_VERNOTIF:
+		return scriptError(ErrBadOpcode, "%s is invalid", OpcodeName(op))
+	default:
+		return scriptError(ErrBadOpcode, "0x%02x not a Script Flow control op", op)
+	}
+	return nil
+}
+
 // OpStack implements all script operations that are stack
 // check https://en.bitcoin.it/wiki/Script#Stack
-func OpStack(op byte, main, alt *stack) error {
+func OpStack(op byte, main, alt *Stack, requireMinimal bool) error {
 	switch op {
 	case OP_TOALTSTACK:
-		b1 := main.Pop()
+		b1, err := main.Pop()
+		if err != nil {
+			return err
+		}
 		alt.PushSlice(b1)
 	case OP_FROMALTSTACK:
-		b1 := alt.Pop()
+		b1, err := alt.Pop()
+		if err != nil {
+			return scriptError(ErrInvalidAltStackOperation, "OP_FROMALTSTACK with empty alt stack")
+		}
 		main.PushSlice(b1)
 	case OP_IFDUP:
-		b1 := main.Top()
+		b1, err := main.Top()
+		if err != nil {
+			return err
+		}
 		if slice2bool(b1) {
 			main.PushSlice(b1)
 		}
 	case OP_DEPTH:
-		n := main.ItemsCount()
-		main.PushSlice(n.Bytes())
+		main.PushSlice(NewScriptInt(int64(main.Len())).Bytes())
 	case OP_DROP:
-		main.Pop()
+		_, err := main.Pop()
+		return err
 	case OP_DUP:
-		b1 := main.Top()
+		b1, err := main.Top()
+		if err != nil {
+			return err
+		}
 		main.PushSlice(b1)
 	case OP_NIP:
-		b2 := main.Pop()
-		main.Pop()
-		main.PushSlice(b2)
+		_, err := main.Remove(1)
+		return err
 	case OP_OVER:
-		b2 := main.Pop()
-		b1 := main.Top()
-		main.PushSlice(b2)
+		b1, err := main.Item(1)
+		if err != nil {
+			return err
+		}
 		main.PushSlice(b1)
-	case OP_PICK:
-		n := ScriptIntFromSlice(main.Pop()).Int()
-		bn := main.Item(n)
-		main.PushSlice(bn)
-	case OP_ROLL:
-		n := ScriptIntFromSlice(main.Pop()).Int()
-		b := make([][]byte, n)
-		for i := 0; i < n; i++ {
-			b[i] = main.Pop()
+	case OP_PICK, OP_ROLL:
+		b, err := main.Pop()
+		if err != nil {
+			return err
 		}
-		bn := main.Pop()
-		for i := n - 1; i >= 0; i-- {
-			main.PushSlice(b[i])
+		v, err := ParseScriptInt(b, DefaultScriptIntSize, requireMinimal)
+		if err != nil {
+			return err
+		}
+		n := v.Int()
+		if n < 0 || n >= main.Len() {
+			return scriptError(ErrInvalidStackOperation, "%s: bad item index %d", OpcodeName(op), n)
+		}
+		var bn []byte
+		if op == OP_PICK {
+			bn, err = main.Item(n)
+		} else {
+			bn, err = main.Remove(n)
+		}
+		if err != nil {
+			return err
 		}
 		main.PushSlice(bn)
 	case OP_ROT:
-		b3 := main.Pop()
-		b2 := main.Pop()
-		b1 := main.Pop()
-		main.PushSlice(b2)
-		main.PushSlice(b3)
-		main.PushSlice(b1)
+		b, err := main.PopN(3)
+		if err != nil {
+			return err
+		}
+		main.PushSlice(b[1])
+		main.PushSlice(b[2])
+		main.PushSlice(b[0])
 	case OP_SWAP:
-		b2 := main.Pop()
-		b1 := main.Pop()
-		main.PushSlice(b2)
-		main.PushSlice(b1)
+		b, err := main.PopN(2)
+		if err != nil {
+			return err
+		}
+		main.PushSlice(b[1])
+		main.PushSlice(b[0])
 	case OP_TUCK:
-		b2 := main.Pop()
-		b1 := main.Pop()
-		main.PushSlice(b2)
-		main.PushSlice(b1)
-		main.PushSlice(b2)
+		b, err := main.PopN(2)
+		if err != nil {
+			return err
+		}
+		main.PushSlice(b[1])
+		main.PushSlice(b[0])
+		main.PushSlice(b[1])
 	case OP_2DROP:
-		main.Pop()
-		main.Pop()
+		_, err := main.PopN(2)
+		return err
 	case OP_2DUP:
-		b2 := main.Pop()
-		b1 := main.Pop()
-		main.PushSlice(b1)
-		main.PushSlice(b2)
-		main.PushSlice(b1)
-		main.PushSlice(b2)
+		b, err := main.PopN(2)
+		if err != nil {
+			return err
+		}
+		main.PushSlice(b[0])
+		main.PushSlice(b[1])
+		main.PushSlice(b[0])
+		main.PushSlice(b[1])
 	case OP_3DUP:
-		b3 := main.Pop()
-		b2 := main.Pop()
-		b1 := main.Pop()
-		main.PushSlice(b1)
-		main.PushSlice(b2)
-		main.PushSlice(b3)
-		main.PushSlice(b1)
-		main.PushSlice(b2)
-		main.PushSlice(b3)
+		b, err := main.PopN(3)
+		if err != nil {
+			return err
+		}
+		main.PushSlice(b[0])
+		main.PushSlice(b[1])
+		main.PushSlice(b[2])
+		main.PushSlice(b[0])
+		main.PushSlice(b[1])
+		main.PushSlice(b[2])
 	case OP_2OVER:
-		b2 := main.Item(2)
-		b1 := main.Item(3)
-		main.PushSlice(b1)
-		main.PushSlice(b2)
+		b, err := main.PopN(4)
+		if err != nil {
+			return err
+		}
+		for i := range b {
+			main.PushSlice(b[i])
+		}
+		main.PushSlice(b[0])
+		main.PushSlice(b[1])
 	case OP_2ROT:
-		b6 := main.Pop()
-		b5 := main.Pop()
-		b4 := main.Pop()
-		b3 := main.Pop()
-		b2 := main.Pop()
-		b1 := main.Pop()
-		main.PushSlice(b3)
-		main.PushSlice(b4)
-		main.PushSlice(b5)
-		main.PushSlice(b6)
-		main.PushSlice(b1)
-		main.PushSlice(b2)
+		b, err := main.PopN(6)
+		if err != nil {
+			return err
+		}
+		main.PushSlice(b[2])
+		main.PushSlice(b[3])
+		main.PushSlice(b[4])
+		main.PushSlice(b[5])
+		main.PushSlice(b[0])
+		main.PushSlice(b[1])
 	case OP_2SWAP:
-		b4 := main.Pop()
-		b3 := main.Pop()
-		b2 := main.Pop()
-		b1 := main.Pop()
-		main.PushSlice(b3)
-		main.PushSlice(b4)
-		main.PushSlice(b1)
-		main.PushSlice(b2)
+		b, err := main.PopN(4)
+		if err != nil {
+			return err
+		}
+		main.PushSlice(b[2])
+		main.PushSlice(b[3])
+		main.PushSlice(b[0])
+		main.PushSlice(b[1])
 	default:
-		return fmt.Errorf("0x%02x not a Script Stack op", op)
+		return scriptError(ErrBadOpcode, "0x%02x not a Script Stack op", op)
 	}
 	return nil
 }
 
 // OpSplice implements all script operations that are Splice
 // check https://en.bitcoin.it/wiki/Script#Splice
-func OpSplice(op byte, main, alt *stack) error {
+func OpSplice(op byte, main, alt *Stack) error {
 	switch op {
 	case OP_SIZE:
-		b1 := main.Top()
-		n := ScriptInt{int64(len(b1))}
-		main.PushSlice(n.Bytes())
+		b1, err := main.Top()
+		if err != nil {
+			return err
+		}
+		main.PushSlice(NewScriptInt(int64(len(b1))).Bytes())
+	case OP_CAT, OP_SUBSTR, OP_LEFT, OP_RIGHT:
+		return disabledOpcode(op)
 	default:
-		return fmt.Errorf("0x%02x not a Script Splice op", op)
+		return scriptError(ErrBadOpcode, "0x%02x not a Script Splice op", op)
 	}
 	return nil
 }
 
 // OpBitwise implements all script operations that are Bitwise logic
 // check https://en.bitcoin.it/wiki/Script#Bitwise_logic
-func OpBitwise(op byte, main, alt *stack) error {
+func OpBitwise(op byte, main, alt *Stack) error {
 	switch op {
-	case OP_EQUAL:
-		b1 := main.Pop()
-		b2 := main.Pop()
-		if bytes.Compare(b1, b2) == 0 {
-			main.PushByte(1)
-		} else {
-			main.PushByte(0)
+	case OP_INVERT, OP_AND, OP_OR, OP_XOR:
+		return disabledOpcode(op)
+	case OP_EQUAL, OP_EQUALVERIFY:
+		b, err := main.PopN(2)
+		if err != nil {
+			return err
 		}
-	case OP_EQUALVERIFY:
-		b1 := main.Pop()
-		b2 := main.Pop()
-		if bytes.Compare(b1, b2) != 0 {
-			return InvalidTransaction
+		equal := bytes.Equal(b[0], b[1])
+		if op == OP_EQUALVERIFY {
+			if !equal {
+				return scriptError(ErrEqualVerify, "OP_EQUALVERIFY failed")
+			}
+			return nil
 		}
+		pushBool(main, equal)
 	default:
-		return fmt.Errorf("0x%02x not a Script Bitwise logic op", op)
+		return scriptError(ErrBadOpcode, "0x%02x not a Script Bitwise logic op", op)
 	}
 	return nil
 }
 
 // OpArithmetic implements all script operations that are Arithmetic
 // check https://en.bitcoin.it/wiki/Script#Arithmetic
-func OpArithmetic(op byte, main, alt *stack) error {
+//
+// operands are limited to 4 bytes, when requireMinimal is set they must
+// be encoded minimally as well
+func OpArithmetic(op byte, main, alt *Stack, requireMinimal bool) error {
+	var nArgs int
+	switch {
+	case IsDisabledOpcode(op):
+		return disabledOpcode(op)
+	case OP_1ADD <= op && op <= OP_0NOTEQUAL:
+		nArgs = 1
+	case OP_ADD <= op && op <= OP_MAX:
+		nArgs = 2
+	case op == OP_WITHIN:
+		nArgs = 3
+	default:
+		return scriptError(ErrBadOpcode, "0x%02x not a Script Arithmetic op", op)
+	}
+	b, err := main.PopN(nArgs)
+	if err != nil {
+		return err
+	}
+	args := make([]int64, nArgs)
+	for i := range b {
+		n, err := ParseScriptInt(b[i], DefaultScriptIntSize, requireMinimal)
+		if err != nil {
+			return err
+		}
+		args[i] = n.Int64()
+	}
+	var res int64
 	switch op {
 	case OP_1ADD:
-		n := ScriptIntFromSlice(main.Pop())
-		n.val++
-		main.PushSlice(n.Bytes())
+		res = args[0] + 1
 	case OP_1SUB:
-		n := ScriptIntFromSlice(main.Pop())
-		n.val--
-		main.PushSlice(n.Bytes())
+		res = args[0] - 1
 	case OP_NEGATE:
-		b := main.Pop()
-		n := len(b)
-		if n > 0 {
-			if b[n-1]&0x80 > 0 {
-				b[n-1] = b[n-1] & 0x7f
-			} else {
-				b[n-1] = b[n-1] | 0x80
-			}
-		}
-		main.PushSlice(b)
+		res = -args[0]
 	case OP_ABS:
-		b := main.Pop()
-		n := len(b)
-		if n > 0 {
-			b[n-1] = b[n-1] & 0x7f
+		res = args[0]
+		if res < 0 {
+			res = -res
 		}
-		main.PushSlice(b)
 	case OP_NOT:
-		n := ScriptIntFromSlice(main.Pop()).Int()
-		if n == 0 {
-			main.PushByte(1)
-		} else {
-			main.PushByte(0)
-		}
+		res = bool2int(args[0] == 0)
 	case OP_0NOTEQUAL:
-		n := ScriptIntFromSlice(main.Pop()).Int()
-		if n == 0 {
-			main.PushByte(0)
-		} else {
-			main.PushByte(1)
-		}
+		res = bool2int(args[0] != 0)
 	case OP_ADD:
-		b := ScriptIntFromSlice(main.Pop()).Int64()
-		a := ScriptIntFromSlice(main.Pop()).Int64()
-		c := ScriptInt{a + b}
-		main.PushSlice(c.Bytes())
+		res = args[0] + args[1]
 	case OP_SUB:
-		b := ScriptIntFromSlice(main.Pop()).Int64()
-		a := ScriptIntFromSlice(main.Pop()).Int64()
-		c := ScriptInt{a - b}
-		main.PushSlice(c.Bytes())
+		res = args[0] - args[1]
 	case OP_BOOLAND:
-		b := slice2bool(main.Pop())
-		a := slice2bool(main.Pop())
-		if a && b {
-			main.PushByte(1)
-		} else {
-			main.PushByte(0)
-		}
+		res = bool2int(args[0] != 0 && args[1] != 0)
 	case OP_BOOLOR:
-		b := slice2bool(main.Pop())
-		a := slice2bool(main.Pop())
-		if a || b {
-			main.PushByte(1)
-		} else {
-			main.PushByte(0)
-		}
+		res = bool2int(args[0] != 0 || args[1] != 0)
 	case OP_NUMEQUAL:
-		b := ScriptIntFromSlice(main.Pop()).Int64()
-		a := ScriptIntFromSlice(main.Pop()).Int64()
-		if a == b {
-			main.PushByte(1)
-		} else {
-			main.PushByte(0)
-		}
+		res = bool2int(args[0] == args[1])
 	case OP_NUMEQUALVERIFY:
-		b := ScriptIntFromSlice(main.Pop()).Int64()
-		a := ScriptIntFromSlice(main.Pop()).Int64()
-		if a != b {
-			return InvalidTransaction
+		if args[0] != args[1] {
+			return scriptError(ErrNumEqualVerify, "OP_NUMEQUALVERIFY failed")
 		}
+		return nil
 	case OP_NUMNOTEQUAL:
-		b := ScriptIntFromSlice(main.Pop()).Int64()
-		a := ScriptIntFromSlice(main.Pop()).Int64()
-		if a != b {
-			main.PushByte(1)
-		} else {
-			main.PushByte(0)
-		}
+		res = bool2int(args[0] != args[1])
 	case OP_LESSTHAN:
-		b := ScriptIntFromSlice(main.Pop()).Int64()
-		a := ScriptIntFromSlice(main.Pop()).Int64()
-		if a < b {
-			main.PushByte(1)
-		} else {
-			main.PushByte(0)
-		}
+		res = bool2int(args[0] < args[1])
 	case OP_GREATERTHAN:
-		b := ScriptIntFromSlice(main.Pop()).Int64()
-		a := ScriptIntFromSlice(main.Pop()).Int64()
-		if a > b {
-			main.PushByte(1)
-		} else {
-			main.PushByte(0)
-		}
+		res = bool2int(args[0] > args[1])
 	case OP_LESSTHANOREQUAL:
-		b := ScriptIntFromSlice(main.Pop()).Int64()
-		a := ScriptIntFromSlice(main.Pop()).Int64()
-		if a <= b {
-			main.PushByte(1)
-		} else {
-			main.PushByte(0)
-		}
+		res = bool2int(args[0] <= args[1])
 	case OP_GREATERTHANOREQUAL:
-		b := ScriptIntFromSlice(main.Pop()).Int64()
-		a := ScriptIntFromSlice(main.Pop()).Int64()
-		if a >= b {
-			main.PushByte(1)
-		} else {
-			main.PushByte(0)
-		}
+		res = bool2int(args[0] >= args[1])
 	case OP_MIN:
-		b := ScriptIntFromSlice(main.Pop())
-		a := ScriptIntFromSlice(main.Pop())
-		if a.Int64() < b.Int64() {
-			main.PushSlice(a.Bytes())
-		} else {
-			main.PushSlice(b.Bytes())
+		res = args[0]
+		if args[1] < res {
+			res = args[1]
 		}
 	case OP_MAX:
-		b := ScriptIntFromSlice(main.Pop())
-		a := ScriptIntFromSlice(main.Pop())
-		if a.Int64() > b.Int64() {
-			main.PushSlice(a.Bytes())
-		} else {
-			main.PushSlice(b.Bytes())
+		res = args[0]
+		if args[1] > res {
+			res = args[1]
 		}
 	case OP_WITHIN:
-		max := ScriptIntFromSlice(main.Pop()).Int64()
-		min := ScriptIntFromSlice(main.Pop()).Int64()
-		a := ScriptIntFromSlice(main.Pop()).Int64()
-		if min <= a && a < max {
-			main.PushByte(1)
-		} else {
-			main.PushByte(0)
-		}
-	default:
-		return fmt.Errorf("0x%02x not a Script Arithmetic op", op)
+		res = bool2int(args[1] <= args[0] && args[0] < args[2])
 	}
+	// result is not limited to 4 bytes, but it could not be used as an
+	// operand of the next arithmetic operation if it is longer
+	main.PushSlice(NewScriptInt(res).Bytes())
 	return nil
 }
 
+// IsDisabledOpcode return true for operations which were disabled by
+// the original implementation. Scripts containing them fail even if
+// they are not executed
+func IsDisabledOpcode(op byte) bool {
+	switch op {
+	case OP_CAT, OP_SUBSTR, OP_LEFT, OP_RIGHT,
+		OP_INVERT, OP_AND, OP_OR, OP_XOR,
+		OP_2MUL, OP_2DIV, OP_MUL, OP_DIV, OP_MOD, OP_LSHIFT, OP_RSHIFT:
+		return true
+	}
+	return false
+}
+
+func disabledOpcode(op byte) error {
+	return scriptError(ErrDisabledOpcode, "%s is disabled", OpcodeName(op))
+}
+
+func bool2int(v bool) int64 {
+	if v {
+		return 1
+	}
+	return 0
+}
+
+// pushBool pushes 1 for true and empty item for false, the same as
+// bitcoin core does
+func pushBool(s *Stack, v bool) {
+	if v {
+		s.PushByte(1)
+	} else {
+		s.PushSlice([]byte{})
+	}
+}
+
 // OpCrypto implements all script operations that are Crypto
 // check https://en.bitcoin.it/wiki/Script#Crypto
-func OpCrypto(op byte, main, alt *stack) error {
+func OpCrypto(op byte, main, alt *Stack) error {
+	b, err := main.Pop()
+	if err != nil {
+		return err
+	}
 	switch op {
 	case OP_RIPEMD160:
 		h := ripemd160.New()
-		_, err := h.Write(main.Pop())
+		_, err = h.Write(b)
 		if err != nil {
 			return err
 		}
 		main.PushSlice(h.Sum(nil))
 	case OP_SHA1:
-		sum := sha1.Sum(main.Pop())
+		sum := sha1.Sum(b)
 		main.PushSlice(sum[:])
 	case OP_SHA256:
-		sum := sha256.Sum256(main.Pop())
+		sum := sha256.Sum256(b)
 		main.PushSlice(sum[:])
 	case OP_HASH160:
-		h1 := sha256.New()
-		_, err := h1.Write(main.Pop())
-		if err != nil {
-			return err
-		}
-		h2 := ripemd160.New()
-		_, err = h2.Write(h1.Sum(nil))
-		if err != nil {
-			return err
-		}
-		main.PushSlice(h2.Sum(nil))
+		main.PushSlice(hash160(b))
 	case OP_HASH256:
 		h1 := sha256.New()
-		_, err := h1.Write(main.Pop())
+		_, err = h1.Write(b)
 		if err != nil {
 			return err
 		}
@@ -404,7 +443,7 @@ func OpCrypto(op byte, main, alt *stack) error {
 		}
 		main.PushSlice(h2.Sum(nil))
 	default:
-		return fmt.Errorf("0x%02x not a Script Crypto op", op)
+		return scriptError(ErrBadOpcode, "0x%02x not a Script Crypto op", op)
 	}
 	return nil
 }
@@ -532,6 +571,8 @@ const (
 	OP_NOP8                = 0xb7
 	OP_NOP9                = 0xb8
 	OP_NOP10               = 0xb9
+	// tapscript
+	OP_CHECKSIGADD = 0xba
 	// template matching params
 	OP_SMALLINTEGER = 0xfa
 	OP_PUBKEYS      = 0xfb
@@ -541,6 +582,35 @@ const (
 	OP_INVALIDOPCODE = 0xff
 )
 
+// opcodesByName maps names of operations to opcodes, names are
+// accepted with and without OP_ prefix. Template matching params and
+// OP_INVALIDOPCODE are not operations, the same as in bitcoin core
+var opcodesByName = func() map[string]byte {
+	m := make(map[string]byte)
+	for op := 0; op <= OP_CHECKSIGADD; op++ {
+		name := OpcodeName(byte(op))
+		if strings.HasPrefix(name, "OP_") {
+			m[name] = byte(op)
+		}
+	}
+	// aliases used by bitcoin core
+	m["OP_FALSE"] = OP_FALSE
+	m["OP_TRUE"] = OP_TRUE
+	m["OP_NOP2"] = OP_CHECKLOCKTIMEVERIFY
+	m["OP_NOP3"] = OP_CHECKSEQUENCEVERIFY
+	for name, op := range m {
+		m[strings.TrimPrefix(name, "OP_")] = op
+	}
+	return m
+}()
+
+// OpcodeByName return opcode of operation with name, which is either
+// the one returned by OpcodeName or the same without OP_ prefix
+func OpcodeByName(name string) (op byte, ok bool) {
+	op, ok = opcodesByName[name]
+	return
+}
+
 func OpcodeName(opcode byte) string {
 	switch opcode {
 	case OP_0:
@@ -765,6 +835,8 @@ func OpcodeName(opcode byte) string {
 		return "OP_NOP9"
 	case OP_NOP10:
 		return "OP_NOP10"
+	case OP_CHECKSIGADD:
+		return "OP_CHECKSIGADD"
 	case OP_SMALLINTEGER:
 		return "OP_SMALLINTEGER"
 	case OP_PUBKEYS:
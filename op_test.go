@@ -21,8 +21,8 @@ func hex2byte(hex string) []byte {
 	return b
 }
 
-func StackWithValues(hex ...string) *stack {
-	s := &stack{}
+func StackWithValues(hex ...string) *Stack {
+	s := &Stack{}
 	s.Reset()
 	for i := range hex {
 		s.PushSlice(hex2byte(hex[i]))
@@ -30,16 +30,14 @@ func StackWithValues(hex ...string) *stack {
 	return s
 }
 
-func compareStack(a, b *stack) int {
-	na := a.ItemsCount().Int()
-	nb := b.ItemsCount().Int()
-	if na != nb {
-		return na - nb
+func compareStack(a, b *Stack) int {
+	ia := a.Items()
+	ib := b.Items()
+	if len(ia) != len(ib) {
+		return len(ia) - len(ib)
 	}
-	for i := 0; i < na; i++ {
-		ia := a.item[i]
-		ib := b.item[i]
-		r := bytes.Compare(ia, ib)
+	for i := range ia {
+		r := bytes.Compare(ia[i], ib[i])
 		if r != 0 {
 			return r
 		}
@@ -51,7 +49,7 @@ func TestOpConstants(t *testing.T) {
 	tt := []struct {
 		opcode   byte
 		script   string
-		expected *stack
+		expected *Stack
 	}{
 		{OP_0, "", StackWithValues("")},
 		{0x01, "ab", StackWithValues("ab")},
@@ -62,7 +60,7 @@ func TestOpConstants(t *testing.T) {
 		{OP_1, "", StackWithValues("01")},
 		{OP_16, "", StackWithValues("10")},
 	}
-	s := &stack{}
+	s := &Stack{}
 	for i := range tt {
 		s.Reset()
 		n, err := OpConstants(tt[i].opcode, hex2byte(tt[i].script), s)
@@ -80,11 +78,62 @@ func TestOpConstants(t *testing.T) {
 	}
 }
 
+func TestOpFlowControl(t *testing.T) {
+	tt := []struct {
+		op           byte
+		in, expected *Stack
+		iCond, eCond condStack
+		expect_err   bool
+	}{
+		{OP_IF, StackWithValues("01"), StackWithValues(), condStack{}, condStack{true}, false},
+		{OP_IF, StackWithValues("00"), StackWithValues(), condStack{}, condStack{false}, false},
+		{OP_IF, StackWithValues("0080"), StackWithValues(), condStack{}, condStack{false}, false},
+		{OP_IF, StackWithValues("01"), StackWithValues("01"), condStack{false}, condStack{false, false}, false},
+		{OP_NOTIF, StackWithValues("01"), StackWithValues(), condStack{}, condStack{false}, false},
+		{OP_NOTIF, StackWithValues("00"), StackWithValues(), condStack{}, condStack{true}, false},
+		{OP_ELSE, StackWithValues(), StackWithValues(), condStack{true}, condStack{false}, false},
+		{OP_ELSE, StackWithValues(), StackWithValues(), condStack{true, false}, condStack{true, true}, false},
+		{OP_ELSE, StackWithValues(), StackWithValues(), condStack{}, condStack{}, true},
+		{OP_ENDIF, StackWithValues(), StackWithValues(), condStack{true, false}, condStack{true}, false},
+		{OP_ENDIF, StackWithValues(), StackWithValues(), condStack{}, condStack{}, true},
+		{OP_VERIFY, StackWithValues("01"), StackWithValues(), condStack{}, condStack{}, false},
+		{OP_VERIFY, StackWithValues("00"), StackWithValues(), condStack{}, condStack{}, true},
+		{OP_RETURN, StackWithValues(), StackWithValues(), condStack{}, condStack{}, true},
+		{OP_VERIF, StackWithValues(), StackWithValues(), condStack{false}, condStack{false}, true},
+		{OP_VERNOTIF, StackWithValues(), StackWithValues(), condStack{false}, condStack{false}, true},
+	}
+	for i := range tt {
+		cond := tt[i].iCond
+		err := OpFlowControl(tt[i].op, tt[i].in, &cond)
+		if err != nil && tt[i].expect_err == false {
+			t.Errorf("case #%d error: %v", i+1, err)
+		}
+		if err == nil && tt[i].expect_err == true {
+			t.Errorf("case #%d expected to fail", i+1)
+		}
+		if compareStack(tt[i].in, tt[i].expected) != 0 {
+			t.Errorf("case #%d main stack mismatch", i+1)
+			t.Errorf("expected[\n%s]", tt[i].expected)
+			t.Errorf("got[\n%s]", tt[i].in)
+		}
+		if len(cond) != len(tt[i].eCond) {
+			t.Errorf("case #%d cond stack mismatch %v != %v", i+1, tt[i].eCond, cond)
+			continue
+		}
+		for j := range cond {
+			if cond[j] != tt[i].eCond[j] {
+				t.Errorf("case #%d cond stack mismatch %v != %v", i+1, tt[i].eCond, cond)
+				break
+			}
+		}
+	}
+}
+
 func TestOpAltStack(t *testing.T) {
 	tt := []struct {
 		opcode      byte
-		iMain, iAlt *stack
-		eMain, eAlt *stack
+		iMain, iAlt *Stack
+		eMain, eAlt *Stack
 	}{
 		{
 			OP_TOALTSTACK,
@@ -98,7 +147,7 @@ func TestOpAltStack(t *testing.T) {
 		},
 	}
 	for i := range tt {
-		err := OpStack(tt[i].opcode, tt[i].iMain, tt[i].iAlt)
+		err := OpStack(tt[i].opcode, tt[i].iMain, tt[i].iAlt, false)
 		if err != nil {
 			t.Errorf("case #%d error: %v", i+1, err)
 		}
@@ -118,7 +167,7 @@ func TestOpAltStack(t *testing.T) {
 func TestOpStack(t *testing.T) {
 	tt := []struct {
 		op           byte
-		in, expected *stack
+		in, expected *Stack
 	}{
 		{OP_IFDUP, StackWithValues("aa"), StackWithValues("aa", "aa")},
 		{OP_IFDUP, StackWithValues("aa", "00"), StackWithValues("aa", "00")},
@@ -143,10 +192,10 @@ func TestOpStack(t *testing.T) {
 		{OP_2ROT, StackWithValues("01", "02", "03", "04", "05", "06"), StackWithValues("03", "04", "05", "06", "01", "02")},
 		{OP_2SWAP, StackWithValues("01", "02", "03", "04"), StackWithValues("03", "04", "01", "02")},
 	}
-	alt := &stack{}
+	alt := &Stack{}
 	for i := range tt {
 		alt.Reset()
-		err := OpStack(tt[i].op, tt[i].in, alt)
+		err := OpStack(tt[i].op, tt[i].in, alt, false)
 		if err != nil {
 			t.Errorf("case #%d error: %v", i+1, err)
 		}
@@ -155,7 +204,7 @@ func TestOpStack(t *testing.T) {
 			t.Errorf("expected[\n%s]", tt[i].expected)
 			t.Errorf("got[\n%s]", tt[i].in)
 		}
-		if alt.ItemsCount().Int() != 0 {
+		if alt.Len() != 0 {
 			t.Errorf("case #%d alt stack has items", i+1)
 			t.Errorf("\n%s", alt)
 		}
@@ -165,11 +214,11 @@ func TestOpStack(t *testing.T) {
 func TestOpSplice(t *testing.T) {
 	tt := []struct {
 		op           byte
-		in, expected *stack
+		in, expected *Stack
 	}{
 		{OP_SIZE, StackWithValues("0102030405"), StackWithValues("0102030405", "05")},
 	}
-	alt := &stack{}
+	alt := &Stack{}
 	for i := range tt {
 		alt.Reset()
 		err := OpSplice(tt[i].op, tt[i].in, alt)
@@ -181,7 +230,7 @@ func TestOpSplice(t *testing.T) {
 			t.Errorf("expected[\n%s]", tt[i].expected)
 			t.Errorf("got[\n%s]", tt[i].in)
 		}
-		if alt.ItemsCount().Int() != 0 {
+		if alt.Len() != 0 {
 			t.Errorf("case #%d alt stack has items", i+1)
 			t.Errorf("\n%s", alt)
 		}
@@ -191,15 +240,17 @@ func TestOpSplice(t *testing.T) {
 func TestOpBitwise(t *testing.T) {
 	tt := []struct {
 		op           byte
-		in, expected *stack
+		in, expected *Stack
 		expect_err   bool
 	}{
 		{OP_EQUAL, StackWithValues("0102", "0102"), StackWithValues("01"), false},
-		{OP_EQUAL, StackWithValues("0102", "0103"), StackWithValues("00"), false},
+		{OP_EQUAL, StackWithValues("0102", "0103"), StackWithValues(""), false},
 		{OP_EQUALVERIFY, StackWithValues("0102", "0102"), StackWithValues(), false},
 		{OP_EQUALVERIFY, StackWithValues("0102", "0103"), StackWithValues(), true},
+		{OP_AND, StackWithValues("01", "03"), StackWithValues("01", "03"), true},
+		{OP_INVERT, StackWithValues("01"), StackWithValues("01"), true},
 	}
-	alt := &stack{}
+	alt := &Stack{}
 	for i := range tt {
 		alt.Reset()
 		err := OpBitwise(tt[i].op, tt[i].in, alt)
@@ -211,7 +262,7 @@ func TestOpBitwise(t *testing.T) {
 			t.Errorf("expected[\n%s]", tt[i].expected)
 			t.Errorf("got[\n%s]", tt[i].in)
 		}
-		if alt.ItemsCount().Int() != 0 {
+		if alt.Len() != 0 {
 			t.Errorf("case #%d alt stack has items", i+1)
 			t.Errorf("\n%s", alt)
 		}
@@ -221,56 +272,74 @@ func TestOpBitwise(t *testing.T) {
 func TestOpArithmetic(t *testing.T) {
 	tt := []struct {
 		op           byte
-		in, expected *stack
+		in, expected *Stack
 		expect_err   bool
 	}{
 		{OP_1ADD, StackWithValues("01"), StackWithValues("02"), false},
-		{OP_1SUB, StackWithValues("01"), StackWithValues("00"), false},
+		{OP_1SUB, StackWithValues("01"), StackWithValues(""), false},
 		{OP_NEGATE, StackWithValues("01"), StackWithValues("81"), false},
+		{OP_NEGATE, StackWithValues("81"), StackWithValues("01"), false},
+		{OP_NEGATE, StackWithValues("80"), StackWithValues(""), false},
+		{OP_NEGATE, StackWithValues("ffffff7f"), StackWithValues("ffffffff"), false},
 		{OP_ABS, StackWithValues("81"), StackWithValues("01"), false},
-		{OP_NOT, StackWithValues("01"), StackWithValues("00"), false},
+		{OP_ABS, StackWithValues("8080"), StackWithValues("8000"), false},
+		{OP_ABS, StackWithValues("01020304"), StackWithValues("01020304"), false},
+		{OP_ABS, StackWithValues("0102030405"), StackWithValues(), true},
+		{OP_NOT, StackWithValues("01"), StackWithValues(""), false},
 		{OP_NOT, StackWithValues("00"), StackWithValues("01"), false},
-		{OP_NOT, StackWithValues("02"), StackWithValues("00"), false},
-		{OP_0NOTEQUAL, StackWithValues("00"), StackWithValues("00"), false},
+		{OP_NOT, StackWithValues("02"), StackWithValues(""), false},
+		{OP_0NOTEQUAL, StackWithValues("00"), StackWithValues(""), false},
+		{OP_0NOTEQUAL, StackWithValues("80"), StackWithValues(""), false},
 		{OP_0NOTEQUAL, StackWithValues("ab"), StackWithValues("01"), false},
 		{OP_ADD, StackWithValues("01", "02"), StackWithValues("03"), false},
+		{OP_ADD, StackWithValues("ffffff7f", "01"), StackWithValues("0000008000"), false},
+		{OP_ADD, StackWithValues("ffffffff", "81"), StackWithValues("0000008080"), false},
+		{OP_ADD, StackWithValues("81", "01"), StackWithValues(""), false},
+		{OP_ADD, StackWithValues("0000008000", "01"), StackWithValues(), true},
 		{OP_SUB, StackWithValues("03", "01"), StackWithValues("02"), false},
+		{OP_SUB, StackWithValues("01", "03"), StackWithValues("82"), false},
 		{OP_BOOLAND, StackWithValues("ab", "cd"), StackWithValues("01"), false},
-		{OP_BOOLAND, StackWithValues("34", "00"), StackWithValues("00"), false},
-		{OP_BOOLAND, StackWithValues("00", "00"), StackWithValues("00"), false},
+		{OP_BOOLAND, StackWithValues("34", "00"), StackWithValues(""), false},
+		{OP_BOOLAND, StackWithValues("00", "00"), StackWithValues(""), false},
 		{OP_BOOLOR, StackWithValues("ab", "cd"), StackWithValues("01"), false},
 		{OP_BOOLOR, StackWithValues("34", "00"), StackWithValues("01"), false},
-		{OP_BOOLOR, StackWithValues("00", "00"), StackWithValues("00"), false},
+		{OP_BOOLOR, StackWithValues("00", "00"), StackWithValues(""), false},
 		{OP_NUMEQUAL, StackWithValues("0102", "0102"), StackWithValues("01"), false},
-		{OP_NUMEQUAL, StackWithValues("01", "02"), StackWithValues("00"), false},
+		{OP_NUMEQUAL, StackWithValues("01", "02"), StackWithValues(""), false},
 		{OP_NUMEQUALVERIFY, StackWithValues("01", "01"), StackWithValues(), false},
 		{OP_NUMEQUALVERIFY, StackWithValues("01", "02"), StackWithValues(), true},
-		{OP_NUMNOTEQUAL, StackWithValues("0102", "0102"), StackWithValues("00"), false},
+		{OP_NUMNOTEQUAL, StackWithValues("0102", "0102"), StackWithValues(""), false},
 		{OP_NUMNOTEQUAL, StackWithValues("01", "02"), StackWithValues("01"), false},
 		{OP_LESSTHAN, StackWithValues("01", "02"), StackWithValues("01"), false},
-		{OP_LESSTHAN, StackWithValues("02", "01"), StackWithValues("00"), false},
-		{OP_GREATERTHAN, StackWithValues("01", "02"), StackWithValues("00"), false},
+		{OP_LESSTHAN, StackWithValues("02", "01"), StackWithValues(""), false},
+		{OP_GREATERTHAN, StackWithValues("01", "02"), StackWithValues(""), false},
 		{OP_GREATERTHAN, StackWithValues("02", "01"), StackWithValues("01"), false},
-		{OP_LESSTHANOREQUAL, StackWithValues("02", "01"), StackWithValues("00"), false},
+		{OP_LESSTHANOREQUAL, StackWithValues("02", "01"), StackWithValues(""), false},
 		{OP_LESSTHANOREQUAL, StackWithValues("01", "01"), StackWithValues("01"), false},
 		{OP_LESSTHANOREQUAL, StackWithValues("01", "02"), StackWithValues("01"), false},
-		{OP_GREATERTHANOREQUAL, StackWithValues("01", "02"), StackWithValues("00"), false},
+		{OP_GREATERTHANOREQUAL, StackWithValues("01", "02"), StackWithValues(""), false},
 		{OP_GREATERTHANOREQUAL, StackWithValues("01", "01"), StackWithValues("01"), false},
 		{OP_GREATERTHANOREQUAL, StackWithValues("02", "01"), StackWithValues("01"), false},
 		{OP_MIN, StackWithValues("01", "02"), StackWithValues("01"), false},
+		{OP_MIN, StackWithValues("0100", "81"), StackWithValues("81"), false},
 		{OP_MAX, StackWithValues("01", "02"), StackWithValues("02"), false},
 		{OP_WITHIN, StackWithValues("01", "00", "02"), StackWithValues("01"), false},
 		{OP_WITHIN, StackWithValues("00", "00", "02"), StackWithValues("01"), false},
-		{OP_WITHIN, StackWithValues("03", "00", "02"), StackWithValues("00"), false},
-		{OP_WITHIN, StackWithValues("02", "00", "02"), StackWithValues("00"), false},
+		{OP_WITHIN, StackWithValues("03", "00", "02"), StackWithValues(""), false},
+		{OP_WITHIN, StackWithValues("02", "00", "02"), StackWithValues(""), false},
+		{OP_2MUL, StackWithValues("01"), StackWithValues("01"), true},
+		{OP_MUL, StackWithValues("02", "03"), StackWithValues("02", "03"), true},
+		{OP_RSHIFT, StackWithValues("02", "01"), StackWithValues("02", "01"), true},
 	}
-	main := &stack{}
-	alt := &stack{}
+	main := &Stack{}
+	alt := &Stack{}
 	for i := range tt {
 		alt.Reset()
-		main.i = tt[i].in.i
-		copy(main.item[:], tt[i].in.item[:])
-		err := OpArithmetic(tt[i].op, main, alt)
+		main.Reset()
+		for _, b := range tt[i].in.Items() {
+			main.PushSlice(b)
+		}
+		err := OpArithmetic(tt[i].op, main, alt, false)
 		if err != nil && tt[i].expect_err == false {
 			t.Fatalf("case #%d error: %v", i+1, err)
 		}
@@ -281,7 +350,7 @@ func TestOpArithmetic(t *testing.T) {
 				"got[\n%s]",
 				i+1, OpcodeName(tt[i].op), tt[i].in, tt[i].expected, main)
 		}
-		if alt.ItemsCount().Int() != 0 {
+		if alt.Len() != 0 {
 			t.Errorf("case #%d alt stack has items", i+1)
 			t.Errorf("\n%s", alt)
 		}
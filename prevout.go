@@ -0,0 +1,102 @@
+//
+// prevout.go
+// Copyright (C) 2017 weirdgiraffe <giraffe@cyberzoo.xyz>
+//
+// Distributed under terms of the MIT license.
+//
+
+package bitcoin
+
+import (
+	"errors"
+	"fmt"
+)
+
+// MissingPrevOut is returned when output spent by transaction input is
+// not found
+var MissingPrevOut = errors.New("Missing previous output")
+
+// PrevOutFetcher looks up transaction outputs that are spent by
+// transaction inputs
+type PrevOutFetcher interface {
+	// FetchPrevOut return output number indx of transaction with hash.
+	// Error wraps MissingPrevOut if output is not known
+	FetchPrevOut(hash DoubleHash, indx uint32) (*TxOut, error)
+}
+
+// OutPoint refers to output number Index of transaction Hash
+type OutPoint struct {
+	Hash  DoubleHash
+	Index uint32
+}
+
+func (p OutPoint) String() string {
+	return fmt.Sprintf("%s:%d", p.Hash, p.Index)
+}
+
+func missingPrevOut(hash DoubleHash, indx uint32) error {
+	return fmt.Errorf("%s: %w", OutPoint{hash, indx}, MissingPrevOut)
+}
+
+// noPrevOutFetcher is returned when spent outputs have to be looked up
+// without PrevOutFetcher
+func noPrevOutFetcher() error {
+	return fmt.Errorf("PrevOutFetcher is not set: %w", MissingPrevOut)
+}
+
+// PrevOutMap is a PrevOutFetcher backed by a map of outputs
+type PrevOutMap map[OutPoint]*TxOut
+
+func (m PrevOutMap) FetchPrevOut(hash DoubleHash, indx uint32) (*TxOut, error) {
+	out, ok := m[OutPoint{hash, indx}]
+	if !ok {
+		return nil, missingPrevOut(hash, indx)
+	}
+	return out, nil
+}
+
+// AddTx adds all outputs of tx to the map
+func (m PrevOutMap) AddTx(tx *Tx) {
+	for i := range tx.Out {
+		m[OutPoint{tx.Hash, uint32(i)}] = &tx.Out[i]
+	}
+}
+
+// TxPrevOuts is a PrevOutFetcher which keeps whole transactions in
+// memory and looks up their outputs. Outputs of unknown transactions
+// are looked up with the fallback fetcher if it is set
+type TxPrevOuts struct {
+	tx       map[DoubleHash]*Tx
+	fallback PrevOutFetcher
+}
+
+// NewTxPrevOuts return fetcher of txs outputs, fallback may be nil
+func NewTxPrevOuts(fallback PrevOutFetcher, txs ...*Tx) *TxPrevOuts {
+	p := &TxPrevOuts{
+		tx:       make(map[DoubleHash]*Tx, len(txs)),
+		fallback: fallback,
+	}
+	for i := range txs {
+		p.Add(txs[i])
+	}
+	return p
+}
+
+// Add makes outputs of tx available for lookup
+func (p *TxPrevOuts) Add(tx *Tx) {
+	p.tx[tx.Hash] = tx
+}
+
+func (p *TxPrevOuts) FetchPrevOut(hash DoubleHash, indx uint32) (*TxOut, error) {
+	tx, ok := p.tx[hash]
+	if !ok {
+		if p.fallback != nil {
+			return p.fallback.FetchPrevOut(hash, indx)
+		}
+		return nil, missingPrevOut(hash, indx)
+	}
+	if int64(indx) >= int64(len(tx.Out)) {
+		return nil, missingPrevOut(hash, indx)
+	}
+	return &tx.Out[indx], nil
+}
@@ -0,0 +1,56 @@
+//
+// prevout_test.go
+// Copyright (C) 2017 weirdgiraffe <giraffe@cyberzoo.xyz>
+//
+// Distributed under terms of the MIT license.
+//
+
+package bitcoin
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPrevOutFetchers(t *testing.T) {
+	tx1 := &Tx{Hash: DoubleHash{1}, Out: []TxOut{{Value: 10}, {Value: 20}}}
+	tx2 := &Tx{Hash: DoubleHash{2}, Out: []TxOut{{Value: 30}}}
+	m := make(PrevOutMap)
+	m.AddTx(tx2)
+	fetchers := []PrevOutFetcher{
+		PrevOutMap{{tx1.Hash, 0}: &tx1.Out[0], {tx1.Hash, 1}: &tx1.Out[1], {tx2.Hash, 0}: &tx2.Out[0]},
+		NewTxPrevOuts(nil, tx1, tx2),
+		NewTxPrevOuts(m, tx1),
+	}
+	tt := []struct {
+		hash       DoubleHash
+		indx       uint32
+		value      uint64
+		expect_err bool
+	}{
+		{tx1.Hash, 0, 10, false},
+		{tx1.Hash, 1, 20, false},
+		{tx2.Hash, 0, 30, false},
+		{tx1.Hash, 2, 0, true},
+		{tx2.Hash, 0xffffffff, 0, true},
+		{DoubleHash{3}, 0, 0, true},
+	}
+	for j, f := range fetchers {
+		for i := range tt {
+			out, err := f.FetchPrevOut(tt[i].hash, tt[i].indx)
+			if tt[i].expect_err {
+				if !errors.Is(err, MissingPrevOut) {
+					t.Errorf("fetcher #%d case #%d: expected MissingPrevOut, got %v", j, i, err)
+				}
+				continue
+			}
+			if err != nil {
+				t.Errorf("fetcher #%d case #%d: unexpected error: %v", j, i, err)
+				continue
+			}
+			if out.Value != tt[i].value {
+				t.Errorf("fetcher #%d case #%d: expected value %d, got %d", j, i, tt[i].value, out.Value)
+			}
+		}
+	}
+}
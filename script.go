@@ -0,0 +1,80 @@
+//
+// script.go
+// Copyright (C) 2017 weirdgiraffe <giraffe@cyberzoo.xyz>
+//
+// Distributed under terms of the MIT license.
+//
+
+package bitcoin
+
+// IsPayToScriptHash return true if script is a BIP16 template
+// OP_HASH160 <20 bytes> OP_EQUAL
+func IsPayToScriptHash(script []byte) bool {
+	return len(script) == 23 &&
+		script[0] == OP_HASH160 &&
+		script[1] == 0x14 &&
+		script[22] == OP_EQUAL
+}
+
+// IsPushOnly return true if script consists only of push operations
+func IsPushOnly(script []byte) bool {
+	t := NewScriptTokenizer(script)
+	for t.Next() {
+		if t.Opcode() > OP_16 {
+			return false
+		}
+	}
+	return t.Err() == nil
+}
+
+// WitnessProgram return version and program of a witness program
+// script, which is a push of version number followed by a single push
+// of 2 to 40 bytes (BIP141)
+func WitnessProgram(script []byte) (version int, program []byte, ok bool) {
+	if len(script) < 4 || len(script) > 42 {
+		return 0, nil, false
+	}
+	if script[0] != OP_0 && (script[0] < OP_1 || script[0] > OP_16) {
+		return 0, nil, false
+	}
+	if int(script[1])+2 != len(script) {
+		return 0, nil, false
+	}
+	if script[0] != OP_0 {
+		version = int(script[0]-OP_1) + 1
+	}
+	return version, script[2:], true
+}
+
+// MinimalPush return script which pushes data with the smallest
+// possible push operation
+func MinimalPush(data []byte) []byte {
+	switch {
+	case len(data) == 1 && data[0] >= 1 && data[0] <= 16:
+		return []byte{OP_1 + data[0] - 1}
+	case len(data) == 1 && data[0] == 0x81:
+		return []byte{OP_1NEGATE}
+	}
+	return pushData(data)
+}
+
+// isMinimalPush return true if data is pushed by the smallest possible
+// push operation op
+func isMinimalPush(op byte, data []byte) bool {
+	n := len(data)
+	switch {
+	case n == 0:
+		return op == OP_0
+	case n == 1 && data[0] >= 1 && data[0] <= 16:
+		return op == OP_1+data[0]-1
+	case n == 1 && data[0] == 0x81:
+		return op == OP_1NEGATE
+	case n < OP_PUSHDATA1:
+		return int(op) == n
+	case n <= 0xff:
+		return op == OP_PUSHDATA1
+	case n <= 0xffff:
+		return op == OP_PUSHDATA2
+	}
+	return true
+}
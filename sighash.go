@@ -0,0 +1,395 @@
+//
+// sighash.go
+// Copyright (C) 2017 weirdgiraffe <giraffe@cyberzoo.xyz>
+//
+// Distributed under terms of the MIT license.
+//
+
+package bitcoin
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+)
+
+// SigHashType is the last byte of a script signature which defines
+// what parts of transaction are covered by the signature
+type SigHashType uint32
+
+const (
+	// SigHashDefault is an implicit sighash type of 64 byte taproot
+	// signatures, which commits to the whole transaction
+	SigHashDefault      SigHashType = 0x00
+	SigHashAll          SigHashType = 0x01
+	SigHashNone         SigHashType = 0x02
+	SigHashSingle       SigHashType = 0x03
+	SigHashAnyOneCanPay SigHashType = 0x80
+
+	sigHashMask SigHashType = 0x1f
+)
+
+// sigHashTypeNames are the names of defined sighash types of non
+// taproot signatures
+var sigHashTypeNames = map[SigHashType]string{
+	SigHashAll:                          "ALL",
+	SigHashAll | SigHashAnyOneCanPay:    "ALL|ANYONECANPAY",
+	SigHashNone:                         "NONE",
+	SigHashNone | SigHashAnyOneCanPay:   "NONE|ANYONECANPAY",
+	SigHashSingle:                       "SINGLE",
+	SigHashSingle | SigHashAnyOneCanPay: "SINGLE|ANYONECANPAY",
+}
+
+func (t SigHashType) String() string {
+	if name, ok := sigHashTypeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("SigHashType(0x%02x)", uint32(t))
+}
+
+// LegacySignatureHash computes the hash that is signed by signature
+// of input inIndx (pre-segwit algorithm)
+// check https://en.bitcoin.it/wiki/OP_CHECKSIG
+//
+// subScript is a script that is being executed starting after the last
+// executed OP_CODESEPARATOR with signatures already removed from it
+func (tx *Tx) LegacySignatureHash(subScript []byte, inIndx int, hashType SigHashType) (h DoubleHash) {
+	// original client returns 1 instead of an error for bad indexes, so
+	// signature over "1" is valid for such inputs
+	if inIndx < 0 || inIndx >= len(tx.In) {
+		h[0] = 1
+		return h
+	}
+	if hashType&sigHashMask == SigHashSingle && inIndx >= len(tx.Out) {
+		h[0] = 1
+		return h
+	}
+	subScript = removeOpcode(subScript, OP_CODESEPARATOR)
+
+	w := new(bytes.Buffer)
+	err := binary.Write(w, binary.LittleEndian, tx.Version)
+	if err != nil {
+		panic(err)
+	}
+	if hashType&SigHashAnyOneCanPay != 0 {
+		in := tx.In[inIndx]
+		in.Script = subScript
+		err = WriteVarint(w, 1)
+		if err != nil {
+			panic(err)
+		}
+		_, err = w.Write(in.Raw())
+		if err != nil {
+			panic(err)
+		}
+	} else {
+		err = WriteVarint(w, Varint(len(tx.In)))
+		if err != nil {
+			panic(err)
+		}
+		for i := range tx.In {
+			in := tx.In[i]
+			if i == inIndx {
+				in.Script = subScript
+			} else {
+				in.Script = nil
+				switch hashType & sigHashMask {
+				case SigHashNone, SigHashSingle:
+					in.SequenceNum = 0
+				}
+			}
+			_, err = w.Write(in.Raw())
+			if err != nil {
+				panic(err)
+			}
+		}
+	}
+	switch hashType & sigHashMask {
+	case SigHashNone:
+		err = WriteVarint(w, 0)
+		if err != nil {
+			panic(err)
+		}
+	case SigHashSingle:
+		err = WriteVarint(w, Varint(inIndx+1))
+		if err != nil {
+			panic(err)
+		}
+		for i := 0; i < inIndx; i++ {
+			out := TxOut{Value: 0xffffffffffffffff}
+			_, err = w.Write(out.Raw())
+			if err != nil {
+				panic(err)
+			}
+		}
+		_, err = w.Write(tx.Out[inIndx].Raw())
+		if err != nil {
+			panic(err)
+		}
+	default:
+		err = WriteVarint(w, Varint(len(tx.Out)))
+		if err != nil {
+			panic(err)
+		}
+		for i := range tx.Out {
+			_, err = w.Write(tx.Out[i].Raw())
+			if err != nil {
+				panic(err)
+			}
+		}
+	}
+	err = binary.Write(w, binary.LittleEndian, tx.LockTime)
+	if err != nil {
+		panic(err)
+	}
+	err = binary.Write(w, binary.LittleEndian, uint32(hashType))
+	if err != nil {
+		panic(err)
+	}
+	h.Update(w.Bytes())
+	return h
+}
+
+// removeOpcode return script without any occurrence of opcode op
+func removeOpcode(script []byte, op byte) []byte {
+	if bytes.IndexByte(script, op) < 0 {
+		return script
+	}
+	ret := make([]byte, 0, len(script))
+	t := NewScriptTokenizer(script)
+	for t.Next() {
+		if t.Opcode() != op {
+			ret = append(ret, script[t.Offset():t.End()]...)
+		}
+	}
+	if t.Err() != nil {
+		ret = append(ret, script[t.Offset():]...)
+	}
+	return ret
+}
+
+// removeSignature return script without any push of signature sig
+// check FindAndDelete in https://github.com/bitcoin/bitcoin/blob/master/src/script/interpreter.cpp
+//
+// Empty signature is removed as OP_0, the same as in bitcoin core
+func removeSignature(script []byte, sig []byte) []byte {
+	push := pushData(sig)
+	if bytes.Index(script, push) < 0 {
+		return script
+	}
+	// push of signature is a single operation, so it is enough to
+	// compare whole operations
+	ret := make([]byte, 0, len(script))
+	t := NewScriptTokenizer(script)
+	for t.Next() {
+		op := script[t.Offset():t.End()]
+		if !bytes.Equal(op, push) {
+			ret = append(ret, op...)
+		}
+	}
+	if t.Err() != nil {
+		ret = append(ret, script[t.Offset():]...)
+	}
+	return ret
+}
+
+// pushData return script operation that pushes b to the stack
+func pushData(b []byte) []byte {
+	n := len(b)
+	var ret []byte
+	switch {
+	case n < OP_PUSHDATA1:
+		ret = []byte{byte(n)}
+	case n <= 0xff:
+		ret = []byte{OP_PUSHDATA1, byte(n)}
+	case n <= 0xffff:
+		ret = []byte{OP_PUSHDATA2, 0, 0}
+		binary.LittleEndian.PutUint16(ret[1:], uint16(n))
+	default:
+		ret = []byte{OP_PUSHDATA4, 0, 0, 0, 0}
+		binary.LittleEndian.PutUint32(ret[1:], uint32(n))
+	}
+	return append(ret, b...)
+}
+
+// TxSigHashes holds hashes of transaction parts, which are the same for
+// signature hashes of all inputs. Computing them once per transaction
+// keeps verification of all inputs linear in their number, which is
+// what BIP143 and BIP341 signature hashes are designed for
+type TxSigHashes struct {
+	// single SHA256 is used by BIP341, BIP143 uses double SHA256
+	shaPrevOuts  [32]byte
+	shaSequences [32]byte
+	shaOutputs   [32]byte
+	hashPrevOuts DoubleHash
+	hashSequence DoubleHash
+	hashOutputs  DoubleHash
+
+	// outputs spent by all inputs and their hashes, are known only if
+	// they were passed to NewTxSigHashes
+	spentOuts        []*TxOut
+	shaAmounts       [32]byte
+	shaScriptPubKeys [32]byte
+}
+
+// NewTxSigHashes computes hashes of tx parts. spentOuts are outputs
+// spent by all transaction inputs, which are required for taproot
+// signature hashes only. It could be nil otherwise
+func NewTxSigHashes(tx *Tx, spentOuts []*TxOut) *TxSigHashes {
+	h := &TxSigHashes{
+		shaPrevOuts:  sha256.Sum256(tx.rawPrevOuts()),
+		shaSequences: sha256.Sum256(tx.rawSequences()),
+		shaOutputs:   sha256.Sum256(tx.rawOutputs()),
+	}
+	h.hashPrevOuts = sha256.Sum256(h.shaPrevOuts[:])
+	h.hashSequence = sha256.Sum256(h.shaSequences[:])
+	h.hashOutputs = sha256.Sum256(h.shaOutputs[:])
+	if len(spentOuts) != len(tx.In) {
+		return h
+	}
+	amounts := new(bytes.Buffer)
+	scripts := new(bytes.Buffer)
+	for i := range spentOuts {
+		if spentOuts[i] == nil {
+			return h
+		}
+		binary.Write(amounts, binary.LittleEndian, spentOuts[i].Value)
+		WriteVarint(scripts, Varint(len(spentOuts[i].Script)))
+		scripts.Write(spentOuts[i].Script)
+	}
+	h.spentOuts = spentOuts
+	h.shaAmounts = sha256.Sum256(amounts.Bytes())
+	h.shaScriptPubKeys = sha256.Sum256(scripts.Bytes())
+	return h
+}
+
+// WitnessV0SignatureHash computes the hash that is signed by signature
+// of version 0 witness program input inIndx, which spends amount
+// check https://github.com/bitcoin/bips/blob/master/bip-0143.mediawiki
+//
+// Hashes of transaction parts are computed on every call, use
+// TxSigHashes.WitnessV0SignatureHash to share them between inputs.
+// BadInputIndex is returned if tx has no input inIndx
+func (tx *Tx) WitnessV0SignatureHash(scriptCode []byte, inIndx int, amount uint64, hashType SigHashType) (h DoubleHash, err error) {
+	err = checkInputIndex(tx, inIndx)
+	if err != nil {
+		return h, err
+	}
+	return NewTxSigHashes(tx, nil).WitnessV0SignatureHash(tx, scriptCode, inIndx, amount, hashType)
+}
+
+// WitnessV0SignatureHash is Tx.WitnessV0SignatureHash which uses hashes
+// of tx parts computed in advance
+func (sh *TxSigHashes) WitnessV0SignatureHash(tx *Tx, scriptCode []byte, inIndx int, amount uint64, hashType SigHashType) (h DoubleHash, err error) {
+	err = checkInputIndex(tx, inIndx)
+	if err != nil {
+		return h, err
+	}
+	var hashPrevOuts, hashSequence, hashOutputs DoubleHash
+	base := hashType & sigHashMask
+	if hashType&SigHashAnyOneCanPay == 0 {
+		hashPrevOuts = sh.hashPrevOuts
+		if base != SigHashSingle && base != SigHashNone {
+			hashSequence = sh.hashSequence
+		}
+	}
+	if base != SigHashSingle && base != SigHashNone {
+		hashOutputs = sh.hashOutputs
+	} else if base == SigHashSingle && inIndx < len(tx.Out) {
+		hashOutputs.Update(tx.Out[inIndx].Raw())
+	}
+
+	in := &tx.In[inIndx]
+	w := new(bytes.Buffer)
+	err = binary.Write(w, binary.LittleEndian, tx.Version)
+	if err != nil {
+		panic(err)
+	}
+	_, err = w.Write(hashPrevOuts[:])
+	if err != nil {
+		panic(err)
+	}
+	_, err = w.Write(hashSequence[:])
+	if err != nil {
+		panic(err)
+	}
+	_, err = w.Write(in.PrevTx[:])
+	if err != nil {
+		panic(err)
+	}
+	err = binary.Write(w, binary.LittleEndian, in.PrevTxOutIndx)
+	if err != nil {
+		panic(err)
+	}
+	err = WriteVarint(w, Varint(len(scriptCode)))
+	if err != nil {
+		panic(err)
+	}
+	_, err = w.Write(scriptCode)
+	if err != nil {
+		panic(err)
+	}
+	err = binary.Write(w, binary.LittleEndian, amount)
+	if err != nil {
+		panic(err)
+	}
+	err = binary.Write(w, binary.LittleEndian, in.SequenceNum)
+	if err != nil {
+		panic(err)
+	}
+	_, err = w.Write(hashOutputs[:])
+	if err != nil {
+		panic(err)
+	}
+	err = binary.Write(w, binary.LittleEndian, tx.LockTime)
+	if err != nil {
+		panic(err)
+	}
+	err = binary.Write(w, binary.LittleEndian, uint32(hashType))
+	if err != nil {
+		panic(err)
+	}
+	h.Update(w.Bytes())
+	return h, nil
+}
+
+// rawPrevOuts return serialization of all outpoints spent by tx
+func (tx *Tx) rawPrevOuts() []byte {
+	w := new(bytes.Buffer)
+	for i := range tx.In {
+		_, err := w.Write(tx.In[i].PrevTx[:])
+		if err != nil {
+			panic(err)
+		}
+		err = binary.Write(w, binary.LittleEndian, tx.In[i].PrevTxOutIndx)
+		if err != nil {
+			panic(err)
+		}
+	}
+	return w.Bytes()
+}
+
+// rawSequences return serialization of sequence numbers of all inputs
+func (tx *Tx) rawSequences() []byte {
+	w := new(bytes.Buffer)
+	for i := range tx.In {
+		err := binary.Write(w, binary.LittleEndian, tx.In[i].SequenceNum)
+		if err != nil {
+			panic(err)
+		}
+	}
+	return w.Bytes()
+}
+
+// rawOutputs return serialization of all outputs
+func (tx *Tx) rawOutputs() []byte {
+	w := new(bytes.Buffer)
+	for i := range tx.Out {
+		_, err := w.Write(tx.Out[i].Raw())
+		if err != nil {
+			panic(err)
+		}
+	}
+	return w.Bytes()
+}
@@ -0,0 +1,105 @@
+//
+// sighash_test.go
+// Copyright (C) 2017 weirdgiraffe <giraffe@cyberzoo.xyz>
+//
+// Distributed under terms of the MIT license.
+//
+
+package bitcoin
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+func hex2tx(t *testing.T, hex string) *Tx {
+	tx, err := ReadTx(bytes.NewBuffer(hex2byte(hex)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tx
+}
+
+// cases are taken from bitcoin core src/test/data/sighash.json
+func TestLegacySignatureHash(t *testing.T) {
+	tt := []struct {
+		tx       string
+		script   string
+		inIndx   int
+		hashType int32
+		expected string
+	}{
+		{"907c2bc503ade11cc3b04eb2918b6f547b0630ab569273824748c87ea14b0696526c66ba740200000004ab65ababfd1f9bdd4ef073c7afc4ae00da8a66f429c917a0081ad1e1dabce28d373eab81d8628de802000000096aab5253ab52000052ad042b5f25efb33beec9f3364e8a9139e8439d9d7e26529c3c30b6c3fd89f8684cfd68ea0200000009ab53526500636a52ab599ac2fe02a526ed040000000008535300516352515164370e010000000003006300ab2ec229", "", 2, 1864164639, "31af167a6cf3f9d5f6875caa4d31704ceb0eba078d132b78dab52c3b8997317e"},
+		{"73107cbd025c22ebc8c3e0a47b2a760739216a528de8d4dab5d45cbeb3051cebae73b01ca10200000007ab6353656a636affffffffe26816dffc670841e6a6c8c61c586da401df1261a330a6c6b3dd9f9a0789bc9e000000000800ac6552ac6aac51ffffffff0174a8f0010000000004ac52515100000000", "5163ac63635151ac", 1, 1190874345, "06e328de263a87b09beabe222a21627a6ea5c7f560030da31610c4611f4a46bc"},
+		{"d3b7421e011f4de0f1cea9ba7458bf3486bee722519efab711a963fa8c100970cf7488b7bb0200000003525352dcd61b300148be5d05000000000000000000", "535251536aac536a", 0, -1960128125, "29aa6d2d752d3310eba20442770ad345b7f6a35f96161ede5f07b33e92053e2a"},
+		{"25ee54ef0187387564bb86e0af96baec54289ca8d15e81a507a2ed6668dc92683111dfb7a50100000004005263634cecf17d0429aa4d000000000007636a6aabab5263daa75601000000000251ab4df70a01000000000151980a890400000000065253ac6a006377fd24e3", "65ab", 0, 797877378, "069f38fd5d47abff46f04ee3ae27db03275e9aa4737fa0d2f5394779f9654845"},
+		{"ff5400dd02fec5beb9a396e1cbedc82bedae09ed44bae60ba9bef2ff375a6858212478844b03000000025253ffffffff01e46c203577a79d1172db715e9cc6316b9cfc59b5e5e4d9199fef201c6f9f0f000000000900ab6552656a5165acffffffff02e8ce62040000000002515312ce3e00000000000251513f119316", "", 0, 1541581667, "1e0da47eedbbb381b0e0debbb76e128d042e02e65b11125e17fd127305fc65cd"},
+		{"5d5c41ad0317aa7e40a513f5141ad5fc6e17d3916eebee4ddb400ddab596175b41a111ead20100000005536a5265acffffffff900ecb5e355c5c9f278c2c6ea15ac1558b041738e4bffe5ae06a9346d66d5b2b00000000080000ab636a65ab6affffffff99f4e08305fa5bd8e38fb9ca18b73f7a33c61ff7b3c68e696b30a04fea87f3ca000000000163d3d1760d019fc13a00000000000000000000", "ab53acabab6aac6a52", 2, 1007461922, "4012f5ff2f1238a0eb84854074670b4703238ebc15bfcdcd47ffa8498105fcd9"},
+	}
+	for i := range tt {
+		tx := hex2tx(t, tt[i].tx)
+		h := tx.LegacySignatureHash(hex2byte(tt[i].script), tt[i].inIndx, SigHashType(tt[i].hashType))
+		expected := mustParseHash(tt[i].expected)
+		if h != expected {
+			t.Errorf("case #%d sighash mismatch %x != %x", i+1, expected, h)
+		}
+	}
+}
+
+func TestLegacySignatureHashSingleBug(t *testing.T) {
+	tx := &Tx{In: make([]TxIn, 2), Out: make([]TxOut, 1)}
+	h := tx.LegacySignatureHash(nil, 1, SigHashSingle)
+	one := DoubleHash{1}
+	if h != one {
+		t.Errorf("SIGHASH_SINGLE without matching output must hash to one, got %x", h)
+	}
+	for _, inIndx := range []int{-1, 2} {
+		h = tx.LegacySignatureHash(nil, inIndx, SigHashAll)
+		if h != one {
+			t.Errorf("input %d out of range must hash to one, got %x", inIndx, h)
+		}
+	}
+}
+
+func TestWitnessV0SignatureHash(t *testing.T) {
+	// native P2WPKH example of BIP143
+	tx := hex2tx(t, "0100000002fff7f7881a8099afa6940d42d1e7f6362bec38171ea3edf433541db4e4ad969f"+
+		"0000000000eeffffffef51e1b804cc89d182d279655c3aa89e815b1b309fe287d9b2b55d57b90ec68a"+
+		"0100000000ffffffff02202cb206000000001976a9148280b37df378db99f66f85c95a783a76ac7a6d59"+
+		"88ac9093510d000000001976a9143bde42dbee7e4dbe6a21b2d50ce2f0167faa815988ac11000000")
+	scriptCode := hex2byte("76a9141d0f172a0ecb48aee1be1f2687d2963ae33f71a188ac")
+	h, err := tx.WitnessV0SignatureHash(scriptCode, 1, 600000000, SigHashAll)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(h[:]) != "c37af31116d1b27caf68aae9e3ac82f1477929014d5b917657d0eb49478cb670" {
+		t.Errorf("unexpected hash %x", h[:])
+	}
+	for _, inIndx := range []int{-1, 2} {
+		_, err = tx.WitnessV0SignatureHash(scriptCode, inIndx, 600000000, SigHashAll)
+		if !errors.Is(err, BadInputIndex) {
+			t.Errorf("input %d: expected %v, got %v", inIndx, BadInputIndex, err)
+		}
+	}
+}
+
+func TestRemoveSignature(t *testing.T) {
+	tt := []struct {
+		script, sig, expected string
+	}{
+		{"0201020302010203", "0102", "0302010203"},
+		{"0201020201020302", "0102", "0302"},
+		{"03020102", "0102", "03020102"},
+		{"4c020102ab", "0102", "4c020102ab"},
+		{"ab0201", "0102", "ab0201"},
+		{"ab", "", "ab"},
+	}
+	for i := range tt {
+		got := removeSignature(hex2byte(tt[i].script), hex2byte(tt[i].sig))
+		if !bytes.Equal(got, hex2byte(tt[i].expected)) {
+			t.Errorf("case #%d %x != %s", i+1, got, tt[i].expected)
+		}
+	}
+}
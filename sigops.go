@@ -0,0 +1,128 @@
+//
+// sigops.go
+// Copyright (C) 2017 weirdgiraffe <giraffe@cyberzoo.xyz>
+//
+// Distributed under terms of the MIT license.
+//
+
+package bitcoin
+
+// sigOpCount counts signature operations of script. OP_CHECKMULTISIG
+// counts as maxPubKeysPerMultiSig operations unless accurate is set and
+// it is preceded by OP_1..OP_16. Counting stops at truncated push
+func sigOpCount(script []byte, accurate bool) int {
+	n := 0
+	lastOp := byte(OP_INVALIDOPCODE)
+	t := NewScriptTokenizer(script)
+	for t.Next() {
+		switch t.Opcode() {
+		case OP_CHECKSIG, OP_CHECKSIGVERIFY:
+			n++
+		case OP_CHECKMULTISIG, OP_CHECKMULTISIGVERIFY:
+			if accurate && lastOp >= OP_1 && lastOp <= OP_16 {
+				n += int(lastOp-OP_1) + 1
+			} else {
+				n += maxPubKeysPerMultiSig
+			}
+		}
+		lastOp = t.Opcode()
+	}
+	return n
+}
+
+// lastPush return data pushed by the last operation of push only
+// script, ok is false if script is not push only
+func lastPush(script []byte) (data []byte, ok bool) {
+	t := NewScriptTokenizer(script)
+	for t.Next() {
+		if t.Opcode() > OP_16 {
+			return nil, false
+		}
+		data = t.Data()
+	}
+	return data, t.Err() == nil
+}
+
+// p2shSigOpCount counts signature operations of redeem script pushed by
+// scriptSig spending pay-to-script-hash output
+func p2shSigOpCount(scriptSig []byte) int {
+	redeemScript, ok := lastPush(scriptSig)
+	if !ok {
+		return 0
+	}
+	return sigOpCount(redeemScript, true)
+}
+
+// witnessSigOpCount counts signature operations of input spending
+// witness program, either native or nested into pay-to-script-hash
+func witnessSigOpCount(scriptSig, scriptPubKey []byte, witness [][]byte) int {
+	if IsPayToScriptHash(scriptPubKey) {
+		redeemScript, ok := lastPush(scriptSig)
+		if !ok {
+			return 0
+		}
+		scriptPubKey = redeemScript
+	}
+	version, program, ok := WitnessProgram(scriptPubKey)
+	if !ok || version != 0 {
+		return 0
+	}
+	switch {
+	case len(program) == 20:
+		return 1
+	case len(program) == 32 && len(witness) > 0:
+		return sigOpCount(witness[len(witness)-1], true)
+	}
+	return 0
+}
+
+// LegacySigOpCount return the number of signature operations in input
+// and output scripts, counted the way it was done before BIP16
+func (tx *Tx) LegacySigOpCount() int {
+	n := 0
+	for i := range tx.In {
+		n += sigOpCount(tx.In[i].Script, false)
+	}
+	for i := range tx.Out {
+		n += sigOpCount(tx.Out[i].Script, false)
+	}
+	return n
+}
+
+// SigOpCost return the weighted number of signature operations (BIP141),
+// which includes operations of pay-to-script-hash redeem scripts and
+// witness scripts of spent outputs when flags enable these rules.
+// MissingPrevOut is returned if prevOuts is nil
+func (tx *Tx) SigOpCost(prevOuts PrevOutFetcher, flags ScriptFlags) (int, error) {
+	n := tx.LegacySigOpCount() * WitnessScaleFactor
+	if tx.IsCoinbase() {
+		return n, nil
+	}
+	if prevOuts == nil {
+		return 0, noPrevOutFetcher()
+	}
+	for i := range tx.In {
+		in := &tx.In[i]
+		out, err := prevOuts.FetchPrevOut(in.PrevTx, in.PrevTxOutIndx)
+		if err != nil {
+			return 0, err
+		}
+		if flags&ScriptVerifyP2SH != 0 && IsPayToScriptHash(out.Script) {
+			n += p2shSigOpCount(in.Script) * WitnessScaleFactor
+		}
+		if flags&ScriptVerifyWitness != 0 {
+			n += witnessSigOpCount(in.Script, out.Script, in.Witness)
+		}
+	}
+	return n, nil
+}
+
+// LegacySigOpCount return the number of signature operations of all
+// block transactions, counted the way it was done before BIP16
+func (b *Block) LegacySigOpCount() int {
+	n := 0
+	for i := range b.tx {
+		n += b.tx[i].LegacySigOpCount()
+	}
+	return n
+}
@@ -0,0 +1,92 @@
+//
+// sigops_test.go
+// Copyright (C) 2017 weirdgiraffe <giraffe@cyberzoo.xyz>
+//
+// Distributed under terms of the MIT license.
+//
+
+package bitcoin
+
+import (
+	"crypto/sha256"
+	"errors"
+	"testing"
+)
+
+func TestSigOpCount(t *testing.T) {
+	tt := []struct {
+		script   string
+		legacy   int
+		accurate int
+	}{
+		{"", 0, 0},
+		{"76a914000000000000000000000000000000000000000088ac", 1, 1},
+		{"ad51ae", 21, 2},
+		{"52ae00af", 40, 22},
+		{"ac4c", 1, 1},
+		{"ac4cffac", 1, 1},
+	}
+	for i := range tt {
+		script := hex2byte(tt[i].script)
+		if n := sigOpCount(script, false); n != tt[i].legacy {
+			t.Errorf("case #%d: expected %d legacy sigops, got %d", i, tt[i].legacy, n)
+		}
+		if n := sigOpCount(script, true); n != tt[i].accurate {
+			t.Errorf("case #%d: expected %d accurate sigops, got %d", i, tt[i].accurate, n)
+		}
+	}
+}
+
+func TestSigOpCost(t *testing.T) {
+	multiSig := "52" + "21" + "038479a0fa998cd35259a2ef0a7a5c68662c1474f88ccb6d08a7677bbec7f22041" +
+		"21" + "038479a0fa998cd35259a2ef0a7a5c68662c1474f88ccb6d08a7677bbec7f22041" + "52ae"
+	multiSigHash := hash160(hex2byte(multiSig))
+	h := sha256.Sum256(hex2byte(multiSig))
+	multiSigWitnessHash := h[:]
+	p2wpkh := append([]byte{OP_0, 20}, make([]byte, 20)...)
+	tt := []struct {
+		scriptSig    []byte
+		witness      [][]byte
+		scriptPubKey []byte
+		flags        ScriptFlags
+		cost         int
+	}{
+		// pay-to-pubkey-hash spend counts output sigops only
+		{nil, nil, hex2byte("76a914000000000000000000000000000000000000000088ac"), ScriptVerifyP2SH, 4},
+		// pay-to-script-hash multisig
+		{append([]byte{OP_0}, pushData(hex2byte(multiSig))...), nil,
+			append(append([]byte{OP_HASH160, 20}, multiSigHash...), OP_EQUAL), ScriptVerifyP2SH, 4 + 8},
+		{append([]byte{OP_0}, pushData(hex2byte(multiSig))...), nil,
+			append(append([]byte{OP_HASH160, 20}, multiSigHash...), OP_EQUAL), ScriptVerifyNone, 4},
+		// native and nested witness programs
+		{nil, [][]byte{{}, {}}, p2wpkh, ScriptVerifyP2SH | ScriptVerifyWitness, 4 + 1},
+		{nil, [][]byte{{}, hex2byte(multiSig)},
+			append([]byte{OP_0, 32}, multiSigWitnessHash...), ScriptVerifyP2SH | ScriptVerifyWitness, 4 + 2},
+		{nil, [][]byte{{}, hex2byte(multiSig)},
+			append([]byte{OP_0, 32}, multiSigWitnessHash...), ScriptVerifyP2SH, 4},
+		{pushData(p2wpkh), [][]byte{{}, {}},
+			append(append([]byte{OP_HASH160, 20}, hash160(p2wpkh)...), OP_EQUAL), ScriptVerifyP2SH | ScriptVerifyWitness, 4 + 1},
+	}
+	for i := range tt {
+		prev := DoubleHash{byte(i + 1)}
+		tx := &Tx{
+			Version: 1,
+			In:      []TxIn{{PrevTx: prev, Script: tt[i].scriptSig, Witness: tt[i].witness}},
+			// output with a single OP_CHECKSIG
+			Out: []TxOut{{Script: []byte{OP_CHECKSIG}}},
+		}
+		prevOuts := PrevOutMap{OutPoint{prev, 0}: &TxOut{Script: tt[i].scriptPubKey}}
+		cost, err := tx.SigOpCost(prevOuts, tt[i].flags)
+		if err != nil {
+			t.Errorf("case #%d: unexpected error: %v", i, err)
+			continue
+		}
+		if cost != tt[i].cost {
+			t.Errorf("case #%d: expected cost %d, got %d", i, tt[i].cost, cost)
+		}
+		_, err = tx.SigOpCost(nil, tt[i].flags)
+		if !errors.Is(err, MissingPrevOut) {
+			t.Errorf("case #%d: expected MissingPrevOut, got %v", i, err)
+		}
+	}
+}
@@ -9,51 +9,145 @@ package bitcoin
 
 import "fmt"
 
-type stack struct {
-	i int
-	// size limit is from https://github.com/bitcoin/bitcoin/blob/master/src/script/interpreter.cpp:1031
-	item [1000][]byte
+// size limits are from https://github.com/bitcoin/bitcoin/blob/master/src/script/script.h
+const (
+	// maxStackSize is the maximum number of items on main and alt
+	// stacks together
+	maxStackSize = 1000
+	// maxScriptElementSize is the maximum size of a single stack item
+	maxScriptElementSize = 520
+	// maxScriptSize is the maximum size of non-taproot scripts
+	maxScriptSize = 10000
+)
+
+// Stack is a stack of script data items. Operations that need more
+// items than the stack has return ErrInvalidStackOperation instead of
+// panicking, so scripts could be executed safely. Size limits are
+// enforced by Engine, because they apply to main and alt stacks
+// together
+type Stack struct {
+	items [][]byte
 }
 
-func (s *stack) Reset() {
-	s.i = -1
+// Reset removes all items from the stack
+func (s *Stack) Reset() {
+	s.items = s.items[:0]
 }
 
-func (s *stack) PushSlice(b []byte) {
-	s.i++
-	s.item[s.i] = b
+// PushSlice pushes b on top of the stack
+func (s *Stack) PushSlice(b []byte) {
+	s.items = append(s.items, b)
 }
 
-func (s *stack) PushByte(b byte) {
+// PushByte pushes single byte item on top of the stack
+func (s *Stack) PushByte(b byte) {
 	s.PushSlice([]byte{b})
 }
 
-func (s *stack) Pop() []byte {
-	b := s.item[s.i]
-	s.i--
-	return b
+// Pop removes the top item from the stack
+func (s *Stack) Pop() ([]byte, error) {
+	b, err := s.Top()
+	if err != nil {
+		return nil, err
+	}
+	s.items = s.items[:len(s.items)-1]
+	return b, nil
+}
+
+// PopN removes n top items from the stack and returns them in the
+// stack order, so the top item is the last one
+func (s *Stack) PopN(n int) ([][]byte, error) {
+	if n < 0 || n > len(s.items) {
+		return nil, stackUnderflow(n, len(s.items))
+	}
+	ret := make([][]byte, n)
+	copy(ret, s.items[len(s.items)-n:])
+	s.items = s.items[:len(s.items)-n]
+	return ret, nil
+}
+
+// Top return the top item without removing it
+func (s *Stack) Top() ([]byte, error) {
+	return s.Item(0)
 }
 
-func (s *stack) Top() []byte {
-	return s.item[s.i]
+// Item return n-th item counting from the top, the top item is 0
+func (s *Stack) Item(n int) ([]byte, error) {
+	if n < 0 || n >= len(s.items) {
+		return nil, stackUnderflow(n+1, len(s.items))
+	}
+	return s.items[len(s.items)-1-n], nil
+}
+
+// Remove removes n-th item counting from the top, the top item is 0
+func (s *Stack) Remove(n int) ([]byte, error) {
+	b, err := s.Item(n)
+	if err != nil {
+		return nil, err
+	}
+	i := len(s.items) - 1 - n
+	s.items = append(s.items[:i], s.items[i+1:]...)
+	return b, nil
 }
 
-func (s *stack) Item(n int) []byte {
-	return s.item[s.i-n]
+// Len return number of items on the stack
+func (s *Stack) Len() int {
+	return len(s.items)
 }
 
-func (s *stack) ItemsCount() ScriptInt {
-	return ScriptInt{int64(s.i + 1)}
+// Items return copy of all items, the top item is the last one
+func (s *Stack) Items() [][]byte {
+	ret := make([][]byte, len(s.items))
+	copy(ret, s.items)
+	return ret
 }
 
-func (s *stack) String() string {
+func (s *Stack) String() string {
 	str := ""
-	for i := 0; i <= s.i; i++ {
-		str += fmt.Sprintf("%3d) %s\n", i, byte2hex(s.item[i]))
+	for i := range s.items {
+		str += fmt.Sprintf("%3d) %s\n", i, byte2hex(s.items[i]))
 	}
 	return str
 }
 
+func stackUnderflow(need, have int) *ScriptError {
+	return scriptError(ErrInvalidStackOperation, "Operation requires %d stack items, stack has %d", need, have)
+}
+
+// condStack keeps the execution state of nested OP_IF/OP_NOTIF branches
+// check https://github.com/bitcoin/bitcoin/blob/master/src/script/interpreter.cpp (vfExec)
+type condStack []bool
+
+func (c *condStack) Reset() {
+	*c = (*c)[:0]
+}
+
+func (c *condStack) Push(v bool) {
+	*c = append(*c, v)
+}
+
+func (c *condStack) Pop() {
+	*c = (*c)[:len(*c)-1]
+}
+
+func (c *condStack) Toggle() {
+	(*c)[len(*c)-1] = !(*c)[len(*c)-1]
+}
+
+func (c condStack) Empty() bool {
+	return len(c) == 0
+}
+
+// AllTrue return true if current branch should be executed
+func (c condStack) AllTrue() bool {
+	for i := range c {
+		if !c[i] {
+			return false
+		}
+	}
+	return true
+}
+
 func byte2hex(b []byte) string {
 	s := ""
 	for i := range b {
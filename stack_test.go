@@ -0,0 +1,50 @@
+//
+// stack_test.go
+// Copyright (C) 2017 weirdgiraffe <giraffe@cyberzoo.xyz>
+//
+// Distributed under terms of the MIT license.
+//
+
+package bitcoin
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestStackBounds(t *testing.T) {
+	s := StackWithValues("01", "02", "03")
+	b, err := s.Item(2)
+	if err != nil || !bytes.Equal(b, hex2byte("01")) {
+		t.Errorf("unexpected item %x, error: %v", b, err)
+	}
+	_, err = s.Item(3)
+	if !errors.Is(err, ErrInvalidStackOperation) {
+		t.Errorf("expected %s, got %v", ErrInvalidStackOperation, err)
+	}
+	_, err = s.PopN(4)
+	if !errors.Is(err, ErrInvalidStackOperation) {
+		t.Errorf("expected %s, got %v", ErrInvalidStackOperation, err)
+	}
+	if s.Len() != 3 {
+		t.Errorf("failed operation must not change the stack, got %d items", s.Len())
+	}
+	items, err := s.PopN(2)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if compareStack(StackWithValues(byte2hex(items[0]), byte2hex(items[1])), StackWithValues("02", "03")) != 0 {
+		t.Errorf("items must be returned in the stack order, got %x", items)
+	}
+	b, err = s.Remove(0)
+	if err != nil || !bytes.Equal(b, hex2byte("01")) {
+		t.Errorf("unexpected item %x, error: %v", b, err)
+	}
+	for _, f := range []func() ([]byte, error){s.Pop, s.Top} {
+		_, err = f()
+		if !errors.Is(err, ErrInvalidStackOperation) {
+			t.Errorf("expected %s, got %v", ErrInvalidStackOperation, err)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+//
+// standard.go
+// Copyright (C) 2017 weirdgiraffe <giraffe@cyberzoo.xyz>
+//
+// Distributed under terms of the MIT license.
+//
+
+package bitcoin
+
+import (
+	"fmt"
+)
+
+// ScriptClass is a type of standard output script
+type ScriptClass int
+
+const (
+	NonStandardScript ScriptClass = iota
+	PubKeyScript
+	PubKeyHashScript
+	ScriptHashScript
+	MultiSigScript
+	NullDataScript
+	WitnessV0KeyHashScript
+	WitnessV0ScriptHashScript
+	WitnessV1TaprootScript
+	WitnessUnknownScript
+)
+
+// scriptClassNames are the names used by bitcoin core RPC
+var scriptClassNames = map[ScriptClass]string{
+	NonStandardScript:         "nonstandard",
+	PubKeyScript:              "pubkey",
+	PubKeyHashScript:          "pubkeyhash",
+	ScriptHashScript:          "scripthash",
+	MultiSigScript:            "multisig",
+	NullDataScript:            "nulldata",
+	WitnessV0KeyHashScript:    "witness_v0_keyhash",
+	WitnessV0ScriptHashScript: "witness_v0_scripthash",
+	WitnessV1TaprootScript:    "witness_v1_taproot",
+	WitnessUnknownScript:      "witness_unknown",
+}
+
+func (c ScriptClass) String() string {
+	if name, ok := scriptClassNames[c]; ok {
+		return name
+	}
+	return fmt.Sprintf("ScriptClass(%d)", int(c))
+}
+
+// ScriptInfo is a result of output script classification. Only fields
+// relevant for the class are set
+type ScriptInfo struct {
+	Class ScriptClass
+	// PubKeys of pay-to-pubkey and multisig scripts
+	PubKeys [][]byte
+	// Required is the number of signatures required by multisig script
+	Required int
+	// Hash of public key or script for pay-to-pubkey-hash,
+	// pay-to-script-hash and witness v0 programs
+	Hash []byte
+	// WitnessVersion and WitnessProgram are set for all witness
+	// programs. Program of taproot output is the output public key
+	WitnessVersion int
+	WitnessProgram []byte
+	// NullData are the items pushed by operations following OP_RETURN
+	NullData [][]byte
+}
+
+// scriptTemplates are the standard scripts which do not have a fixed
+// form. Template operations OP_PUBKEY, OP_PUBKEYS, OP_PUBKEYHASH and
+// OP_SMALLINTEGER match data pushes and small numbers
+var scriptTemplates = []struct {
+	class    ScriptClass
+	template []byte
+}{
+	{PubKeyScript, []byte{OP_PUBKEY, OP_CHECKSIG}},
+	{PubKeyHashScript, []byte{OP_DUP, OP_HASH160, OP_PUBKEYHASH, OP_EQUALVERIFY, OP_CHECKSIG}},
+	{MultiSigScript, []byte{OP_SMALLINTEGER, OP_PUBKEYS, OP_SMALLINTEGER, OP_CHECKMULTISIG}},
+}
+
+// ClassifyScript return type of standard output script and the data
+// extracted from it, the same way as Solver of bitcoin core does
+// check https://github.com/bitcoin/bitcoin/blob/master/src/script/solver.cpp
+func ClassifyScript(script []byte) ScriptInfo {
+	if IsPayToScriptHash(script) {
+		return ScriptInfo{Class: ScriptHashScript, Hash: script[2:22]}
+	}
+	if version, program, ok := WitnessProgram(script); ok {
+		info := ScriptInfo{
+			Class:          WitnessUnknownScript,
+			WitnessVersion: version,
+			WitnessProgram: program,
+		}
+		switch {
+		case version == 0 && len(program) == 20:
+			info.Class = WitnessV0KeyHashScript
+			info.Hash = program
+		case version == 0 && len(program) == 32:
+			info.Class = WitnessV0ScriptHashScript
+			info.Hash = program
+		case version == 0:
+			return ScriptInfo{Class: NonStandardScript}
+		case version == 1 && len(program) == 32:
+			info.Class = WitnessV1TaprootScript
+		}
+		return info
+	}
+	// provably unspendable data carrier
+	if len(script) > 0 && script[0] == OP_RETURN {
+		info := ScriptInfo{Class: NullDataScript}
+		t := NewScriptTokenizer(script[1:])
+		for t.Next() {
+			if t.Opcode() > OP_16 {
+				return ScriptInfo{Class: NonStandardScript}
+			}
+			data := t.Data()
+			switch op := t.Opcode(); {
+			case op == OP_1NEGATE:
+				data = []byte{0x81}
+			case op >= OP_1:
+				data = []byte{op - OP_1 + 1}
+			}
+			info.NullData = append(info.NullData, data)
+		}
+		if t.Err() != nil {
+			return ScriptInfo{Class: NonStandardScript}
+		}
+		return info
+	}
+	for i := range scriptTemplates {
+		info := ScriptInfo{Class: scriptTemplates[i].class}
+		if matchTemplate(script, scriptTemplates[i].template, &info) {
+			return info
+		}
+	}
+	return ScriptInfo{Class: NonStandardScript}
+}
+
+// matchTemplate return true if script matches template and fills info
+// with the data matched by template operations
+func matchTemplate(script, template []byte, info *ScriptInfo) bool {
+	var numbers []int
+	t := NewScriptTokenizer(script)
+	next := t.Next()
+	for _, op := range template {
+		switch op {
+		case OP_PUBKEYS:
+			for next && t.Opcode() <= OP_PUSHDATA4 && isValidPubKeySize(t.Data()) {
+				info.PubKeys = append(info.PubKeys, t.Data())
+				next = t.Next()
+			}
+			continue
+		case OP_PUBKEY:
+			// public key has to be pushed directly
+			if !next || int(t.Opcode()) != len(t.Data()) || !isValidPubKeySize(t.Data()) {
+				return false
+			}
+			info.PubKeys = append(info.PubKeys, t.Data())
+		case OP_PUBKEYHASH:
+			if !next || t.Opcode() != 20 {
+				return false
+			}
+			info.Hash = t.Data()
+		case OP_SMALLINTEGER:
+			if !next {
+				return false
+			}
+			n, ok := templateNumber(t.Opcode(), t.Data())
+			if !ok {
+				return false
+			}
+			numbers = append(numbers, n)
+		default:
+			if !next || t.Opcode() != op {
+				return false
+			}
+		}
+		next = t.Next()
+	}
+	if next || t.Err() != nil {
+		return false
+	}
+	if info.Class == MultiSigScript {
+		m, n := numbers[0], numbers[1]
+		if m > n || n != len(info.PubKeys) {
+			return false
+		}
+		info.Required = m
+	}
+	return true
+}
+
+// templateNumber decodes number of signatures or keys of multisig
+// script, which is either small integer operation or a minimal push of
+// number up to 20
+func templateNumber(op byte, data []byte) (int, bool) {
+	var n int
+	switch {
+	case OP_1 <= op && op <= OP_16:
+		n = int(op-OP_1) + 1
+	case op <= OP_PUSHDATA4:
+		if !isMinimalPush(op, data) {
+			return 0, false
+		}
+		v, err := ParseScriptInt(data, DefaultScriptIntSize, true)
+		if err != nil {
+			return 0, false
+		}
+		n = v.Int()
+	default:
+		return 0, false
+	}
+	return n, 1 <= n && n <= maxPubKeysPerMultiSig
+}
+
+// isValidPubKeySize return true if length of public key matches its
+// encoding prefix. The key itself is not validated
+func isValidPubKeySize(pubKey []byte) bool {
+	if len(pubKey) == 0 {
+		return false
+	}
+	switch pubKey[0] {
+	case 0x02, 0x03:
+		return len(pubKey) == 33
+	case 0x04, 0x06, 0x07:
+		return len(pubKey) == 65
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+//
+// standard_test.go
+// Copyright (C) 2017 weirdgiraffe <giraffe@cyberzoo.xyz>
+//
+// Distributed under terms of the MIT license.
+//
+
+package bitcoin
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestClassifyScript(t *testing.T) {
+	pk1 := "038479a0fa998cd35259a2ef0a7a5c68662c1474f88ccb6d08a7677bbec7f22041"
+	pk2 := "04" + strings.Repeat("ab", 64)
+	h20 := "5b6462475454710f3c22f5fdf0b40704c92f25c3"
+	h32 := strings.Repeat("cd", 32)
+	tt := []struct {
+		script   string
+		class    ScriptClass
+		pubKeys  []string
+		required int
+		hash     string
+		version  int
+		program  string
+		nullData []string
+	}{
+		{"", NonStandardScript, nil, 0, "", 0, "", nil},
+		{"21" + pk1 + "ac", PubKeyScript, []string{pk1}, 0, "", 0, "", nil},
+		{"41" + pk2 + "ac", PubKeyScript, []string{pk2}, 0, "", 0, "", nil},
+		{"4c21" + pk1 + "ac", NonStandardScript, nil, 0, "", 0, "", nil},
+		{"21" + "05" + pk1[2:] + "ac", NonStandardScript, nil, 0, "", 0, "", nil},
+		{"76a914" + h20 + "88ac", PubKeyHashScript, nil, 0, h20, 0, "", nil},
+		{"76a914" + h20 + "88ac51", NonStandardScript, nil, 0, "", 0, "", nil},
+		{"76a913" + h20[2:] + "88ac", NonStandardScript, nil, 0, "", 0, "", nil},
+		{"a914" + h20 + "87", ScriptHashScript, nil, 0, h20, 0, "", nil},
+		{"5121" + pk1 + "41" + pk2 + "52ae", MultiSigScript, []string{pk1, pk2}, 1, "", 0, "", nil},
+		{"5221" + pk1 + "41" + pk2 + "52ae", MultiSigScript, []string{pk1, pk2}, 2, "", 0, "", nil},
+		{"5321" + pk1 + "41" + pk2 + "52ae", NonStandardScript, nil, 0, "", 0, "", nil},
+		{"5121" + pk1 + "52ae", NonStandardScript, nil, 0, "", 0, "", nil},
+		{"0021" + pk1 + "51ae", NonStandardScript, nil, 0, "", 0, "", nil},
+		{"5121" + pk1 + "51ae51", NonStandardScript, nil, 0, "", 0, "", nil},
+		{"0014" + h20, WitnessV0KeyHashScript, nil, 0, h20, 0, h20, nil},
+		{"0020" + h32, WitnessV0ScriptHashScript, nil, 0, h32, 0, h32, nil},
+		{"0015" + h20 + "00", NonStandardScript, nil, 0, "", 0, "", nil},
+		{"5120" + h32, WitnessV1TaprootScript, nil, 0, "", 1, h32, nil},
+		{"5114" + h20, WitnessUnknownScript, nil, 0, "", 1, h20, nil},
+		{"6002aabb", WitnessUnknownScript, nil, 0, "", 16, "aabb", nil},
+		{"6a", NullDataScript, nil, 0, "", 0, "", nil},
+		{"6a04deadbeef0051", NullDataScript, nil, 0, "", 0, "", []string{"deadbeef", "", "01"}},
+		{"6a04deadbeef76", NonStandardScript, nil, 0, "", 0, "", nil},
+		{"6a04dead", NonStandardScript, nil, 0, "", 0, "", nil},
+	}
+	for i := range tt {
+		info := ClassifyScript(hex2byte(tt[i].script))
+		if info.Class != tt[i].class {
+			t.Errorf("case #%d expected %s, got %s", i+1, tt[i].class, info.Class)
+			continue
+		}
+		if len(info.PubKeys) != len(tt[i].pubKeys) {
+			t.Errorf("case #%d expected %d keys, got %d", i+1, len(tt[i].pubKeys), len(info.PubKeys))
+		}
+		for j := 0; j < len(info.PubKeys) && j < len(tt[i].pubKeys); j++ {
+			if !bytes.Equal(info.PubKeys[j], hex2byte(tt[i].pubKeys[j])) {
+				t.Errorf("case #%d key %d mismatch: %x", i+1, j, info.PubKeys[j])
+			}
+		}
+		if info.Required != tt[i].required {
+			t.Errorf("case #%d expected %d required, got %d", i+1, tt[i].required, info.Required)
+		}
+		if !bytes.Equal(info.Hash, hex2byte(tt[i].hash)) {
+			t.Errorf("case #%d expected hash %s, got %x", i+1, tt[i].hash, info.Hash)
+		}
+		if info.WitnessVersion != tt[i].version || !bytes.Equal(info.WitnessProgram, hex2byte(tt[i].program)) {
+			t.Errorf("case #%d expected witness %d %s, got %d %x",
+				i+1, tt[i].version, tt[i].program, info.WitnessVersion, info.WitnessProgram)
+		}
+		if len(info.NullData) != len(tt[i].nullData) {
+			t.Errorf("case #%d expected %d data items, got %d", i+1, len(tt[i].nullData), len(info.NullData))
+		}
+		for j := 0; j < len(info.NullData) && j < len(tt[i].nullData); j++ {
+			if !bytes.Equal(info.NullData[j], hex2byte(tt[i].nullData[j])) {
+				t.Errorf("case #%d data %d mismatch: %x", i+1, j, info.NullData[j])
+			}
+		}
+	}
+	if MultiSigScript.String() != "multisig" {
+		t.Errorf("unexpected name %q", MultiSigScript.String())
+	}
+}
@@ -0,0 +1,400 @@
+//
+// taproot.go
+// Copyright (C) 2017 weirdgiraffe <giraffe@cyberzoo.xyz>
+//
+// Distributed under terms of the MIT license.
+//
+
+package bitcoin
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+)
+
+const (
+	// TapLeafTapscript is the leaf version of BIP342 scripts
+	TapLeafTapscript = 0xc0
+	tapLeafMask      = 0xfe
+
+	taprootAnnexTag        = 0x50
+	taprootControlBaseSize = 33
+	taprootControlNodeSize = 32
+	taprootControlMaxNodes = 128
+
+	// every signature check in tapscript consumes validation weight,
+	// which is limited by the witness size
+	validationWeightPerSigOp = 50
+	validationWeightOffset   = 50
+)
+
+// taggedHash computes BIP340 tagged hash of concatenated msg
+func taggedHash(tag string, msg ...[]byte) (h [32]byte) {
+	th := sha256.Sum256([]byte(tag))
+	s := sha256.New()
+	s.Write(th[:])
+	s.Write(th[:])
+	for i := range msg {
+		s.Write(msg[i])
+	}
+	copy(h[:], s.Sum(nil))
+	return h
+}
+
+// TapLeafHash computes hash of script leaf of taproot script tree
+// check https://github.com/bitcoin/bips/blob/master/bip-0341.mediawiki
+func TapLeafHash(leafVersion byte, script []byte) [32]byte {
+	w := new(bytes.Buffer)
+	w.WriteByte(leafVersion)
+	err := WriteVarint(w, Varint(len(script)))
+	if err != nil {
+		panic(err)
+	}
+	w.Write(script)
+	return taggedHash("TapLeaf", w.Bytes())
+}
+
+// TapBranchHash computes hash of inner node of taproot script tree
+func TapBranchHash(a, b []byte) [32]byte {
+	if bytes.Compare(a, b) > 0 {
+		a, b = b, a
+	}
+	return taggedHash("TapBranch", a, b)
+}
+
+// TaprootOutputKey tweaks x-only internalKey with merkleRoot of script
+// tree, which is nil for outputs without scripts
+//
+// return x-only output key and its y coordinate parity
+func TaprootOutputKey(internalKey, merkleRoot []byte) (key []byte, odd bool, err error) {
+	p, err := schnorr.ParsePubKey(internalKey)
+	if err != nil {
+		return nil, false, err
+	}
+	t := taggedHash("TapTweak", internalKey, merkleRoot)
+	var tweak btcec.ModNScalar
+	if overflow := tweak.SetBytes(&t); overflow != 0 {
+		return nil, false, fmt.Errorf("Taproot tweak is out of range")
+	}
+	var pj, tj, q btcec.JacobianPoint
+	p.AsJacobian(&pj)
+	btcec.ScalarBaseMultNonConst(&tweak, &tj)
+	btcec.AddNonConst(&pj, &tj, &q)
+	if (q.X.IsZero() && q.Y.IsZero()) || q.Z.IsZero() {
+		return nil, false, fmt.Errorf("Taproot output key is infinity")
+	}
+	q.ToAffine()
+	return q.X.Bytes()[:], q.Y.IsOdd(), nil
+}
+
+// verifyTaprootCommitment checks that control block proves that script
+// leaf with leafHash is committed to output key program. Invalid
+// internal key is reported as a mismatch, the same as in bitcoin core
+func verifyTaprootCommitment(control, program []byte, leafHash [32]byte) error {
+	k := leafHash
+	path := control[taprootControlBaseSize:]
+	for i := 0; i < len(path); i += taprootControlNodeSize {
+		k = TapBranchHash(k[:], path[i:i+taprootControlNodeSize])
+	}
+	key, odd, err := TaprootOutputKey(control[1:taprootControlBaseSize], k[:])
+	if err != nil {
+		return scriptError(ErrWitnessProgramMismatch, "Taproot internal key is invalid: %v", err)
+	}
+	if !bytes.Equal(key, program) || odd != (control[0]&1 == 1) {
+		return scriptError(ErrWitnessProgramMismatch, "Witness program mismatch")
+	}
+	return nil
+}
+
+// isOpSuccess return true for opcodes that make tapscript unconditionally
+// valid (BIP342)
+func isOpSuccess(op byte) bool {
+	return op == 0x50 || op == 0x62 || (op >= 0x7e && op <= 0x81) ||
+		(op >= 0x83 && op <= 0x86) || (op >= 0x89 && op <= 0x8a) ||
+		(op >= 0x8d && op <= 0x8e) || (op >= 0x95 && op <= 0x99) ||
+		(op >= 0xbb && op <= 0xfe)
+}
+
+// hasOpSuccess return true if script contains any of OP_SUCCESSx
+// opcodes, which make tapscript valid without execution
+func hasOpSuccess(script []byte) (bool, error) {
+	t := NewScriptTokenizer(script)
+	for t.Next() {
+		if isOpSuccess(t.Opcode()) {
+			return true, nil
+		}
+	}
+	return false, t.Err()
+}
+
+// verifyTaproot verifies witness v1 program spending either with
+// signature of output key (key path) or with a committed script
+// (script path)
+// check https://github.com/bitcoin/bips/blob/master/bip-0341.mediawiki
+func (e *Engine) verifyTaproot(witness [][]byte, program []byte) error {
+	if len(witness) == 0 {
+		return scriptError(ErrWitnessProgramWitnessEmpty, "Witness program witness is empty")
+	}
+	weight := witnessSize(witness)
+	e.annex = nil
+	if n := len(witness); n >= 2 && len(witness[n-1]) > 0 && witness[n-1][0] == taprootAnnexTag {
+		e.annex = witness[n-1]
+		witness = witness[:n-1]
+	}
+	err := e.fetchPrevOuts()
+	if err != nil {
+		return err
+	}
+	if len(witness) == 1 {
+		e.sigVersion = sigVersionTaproot
+		return e.checkSchnorrSig(witness[0], program)
+	}
+	control := witness[len(witness)-1]
+	script := witness[len(witness)-2]
+	witness = witness[:len(witness)-2]
+	if len(control) < taprootControlBaseSize ||
+		len(control) > taprootControlBaseSize+taprootControlNodeSize*taprootControlMaxNodes ||
+		(len(control)-taprootControlBaseSize)%taprootControlNodeSize != 0 {
+		return scriptError(ErrTaprootWrongControlSize, "Taproot control block has wrong size %d", len(control))
+	}
+	leafVersion := control[0] & tapLeafMask
+	e.tapLeafHash = TapLeafHash(leafVersion, script)
+	err = verifyTaprootCommitment(control, program, e.tapLeafHash)
+	if err != nil {
+		return err
+	}
+	if leafVersion != TapLeafTapscript {
+		// unknown leaf versions are reserved for future soft forks
+		if e.flags&ScriptVerifyDiscourageUpgradableTaprootVersion != 0 {
+			return scriptError(ErrDiscourageUpgradableTaprootVersion, "Taproot leaf version 0x%02x is unknown", leafVersion)
+		}
+		return nil
+	}
+	e.validationWeight = int64(weight) + validationWeightOffset
+	return e.executeWitnessScript(witness, script, sigVersionTapscript)
+}
+
+// witnessSize return size of serialized witness stack
+func witnessSize(witness [][]byte) int {
+	n := Varint(len(witness)).OutSize()
+	for i := range witness {
+		n += Varint(len(witness[i])).OutSize() + len(witness[i])
+	}
+	return n
+}
+
+// fetchPrevOuts looks up outputs spent by all transaction inputs, which
+// are covered by taproot signatures, unless they are already known
+func (e *Engine) fetchPrevOuts() error {
+	if e.prevOuts == nil {
+		return noPrevOutFetcher()
+	}
+	if e.spentOuts == nil {
+		spentOuts := make([]*TxOut, len(e.tx.In))
+		for i := range e.tx.In {
+			out, err := e.prevOuts.FetchPrevOut(e.tx.In[i].PrevTx, e.tx.In[i].PrevTxOutIndx)
+			if err != nil {
+				return err
+			}
+			spentOuts[i] = out
+		}
+		e.spentOuts = spentOuts
+		// hashes computed without spent outputs lack taproot parts
+		e.sigHashes = nil
+	}
+	e.amount = e.spentOuts[e.inIndx].Value
+	return nil
+}
+
+// checkSchnorrSig verifies BIP340 signature with optional sighash type
+// suffix against x-only pubKey
+func (e *Engine) checkSchnorrSig(sig, pubKey []byte) error {
+	if len(sig) != 64 && len(sig) != 65 {
+		return scriptError(ErrSchnorrSigSize, "Schnorr signature has wrong size %d", len(sig))
+	}
+	hashType := SigHashDefault
+	if len(sig) == 65 {
+		hashType = SigHashType(sig[64])
+		if hashType == SigHashDefault {
+			return scriptError(ErrSchnorrSigHashType, "Signature has explicit SIGHASH_DEFAULT type")
+		}
+		sig = sig[:64]
+	}
+	var leafHash []byte
+	if e.sigVersion == sigVersionTapscript {
+		leafHash = e.tapLeafHash[:]
+	}
+	h, err := e.txSigHashes().TaprootSignatureHash(e.tx, e.inIndx, hashType, e.annex, leafHash, e.codeSepPos)
+	if err != nil {
+		return err
+	}
+	// s must be less than curve order, which is not checked by parser
+	var s btcec.ModNScalar
+	if s.SetByteSlice(sig[32:]) {
+		return scriptError(ErrSchnorrSig, "Schnorr signature is invalid")
+	}
+	ss, err := schnorr.ParseSignature(sig)
+	if err != nil {
+		return scriptError(ErrSchnorrSig, "Schnorr signature is invalid: %v", err)
+	}
+	pk, err := schnorr.ParsePubKey(pubKey)
+	if err != nil {
+		return scriptError(ErrSchnorrSig, "Schnorr public key is invalid: %v", err)
+	}
+	if !ss.Verify(h[:], pk) {
+		return scriptError(ErrSchnorrSig, "Schnorr signature is invalid")
+	}
+	return nil
+}
+
+// checkSigTapscript implements signature check of BIP342. Empty
+// signature is a valid way to fail the check, any other invalid
+// signature fails the script
+func (e *Engine) checkSigTapscript(sig, pubKey []byte) (bool, error) {
+	ok := len(sig) != 0
+	if ok {
+		e.validationWeight -= validationWeightPerSigOp
+		if e.validationWeight < 0 {
+			return false, scriptError(ErrTapscriptValidationWeight, "Tapscript validation weight exceeded")
+		}
+	}
+	switch len(pubKey) {
+	case 0:
+		return false, scriptError(ErrTapscriptEmptyPubKey, "Tapscript public key is empty")
+	case 32:
+		if ok {
+			err := e.checkSchnorrSig(sig, pubKey)
+			if err != nil {
+				return false, err
+			}
+		}
+	default:
+		// unknown public key types are reserved for future soft forks
+		if e.flags&ScriptVerifyDiscourageUpgradablePubKeyType != 0 {
+			return false, scriptError(ErrDiscourageUpgradablePubKeyType, "Tapscript public key type is unknown")
+		}
+	}
+	return ok, nil
+}
+
+// opCheckSigAdd implements OP_CHECKSIGADD
+//
+// stack: <sig> <n> <pubKey> -> <n + 1 if signature is valid>
+func (e *Engine) opCheckSigAdd() error {
+	args, err := e.main.PopN(3)
+	if err != nil {
+		return err
+	}
+	sig, pubKey := args[0], args[2]
+	n, err := e.parseScriptInt(args[1], DefaultScriptIntSize)
+	if err != nil {
+		return err
+	}
+	ok, err := e.checkSigTapscript(sig, pubKey)
+	if err != nil {
+		return err
+	}
+	if ok {
+		n.val++
+	}
+	e.main.PushSlice(n.Bytes())
+	return nil
+}
+
+// TaprootSignatureHash computes the hash that is signed by signature of
+// witness v1 input inIndx (BIP341). spentOuts are outputs spent by all
+// transaction inputs, annex is nil if not present and leafHash is nil
+// for key path spending
+// check https://github.com/bitcoin/bips/blob/master/bip-0341.mediawiki
+//
+// Hashes of transaction parts are computed on every call, use
+// TxSigHashes.TaprootSignatureHash to share them between inputs
+func (tx *Tx) TaprootSignatureHash(inIndx int, spentOuts []*TxOut, hashType SigHashType, annex, leafHash []byte, codeSepPos uint32) (h [32]byte, err error) {
+	if len(spentOuts) != len(tx.In) {
+		return h, fmt.Errorf("Got %d spent outputs for %d inputs", len(spentOuts), len(tx.In))
+	}
+	for i := range spentOuts {
+		if spentOuts[i] == nil {
+			return h, fmt.Errorf("Spent output of input %d is nil", i)
+		}
+	}
+	return NewTxSigHashes(tx, spentOuts).TaprootSignatureHash(tx, inIndx, hashType, annex, leafHash, codeSepPos)
+}
+
+// TaprootSignatureHash is Tx.TaprootSignatureHash which uses hashes of
+// tx parts and spent outputs computed in advance
+func (sh *TxSigHashes) TaprootSignatureHash(tx *Tx, inIndx int, hashType SigHashType, annex, leafHash []byte, codeSepPos uint32) (h [32]byte, err error) {
+	if inIndx < 0 || inIndx >= len(tx.In) {
+		return h, fmt.Errorf("Input index %d is out of range [0, %d)", inIndx, len(tx.In))
+	}
+	if len(sh.spentOuts) != len(tx.In) {
+		return h, fmt.Errorf("Outputs spent by transaction are unknown")
+	}
+	if !(hashType <= SigHashSingle || (hashType >= 0x81 && hashType <= 0x83)) {
+		return h, scriptError(ErrSchnorrSigHashType, "Signature has undefined sighash type 0x%02x", hashType)
+	}
+	outputType := hashType & 3
+	if hashType == SigHashDefault {
+		outputType = SigHashAll
+	}
+	anyoneCanPay := hashType&SigHashAnyOneCanPay != 0
+	if outputType == SigHashSingle && inIndx >= len(tx.Out) {
+		return h, scriptError(ErrSchnorrSigHashType, "SIGHASH_SINGLE without matching output")
+	}
+
+	w := new(bytes.Buffer)
+	// epoch
+	w.WriteByte(0)
+	w.WriteByte(byte(hashType))
+	binary.Write(w, binary.LittleEndian, tx.Version)
+	binary.Write(w, binary.LittleEndian, tx.LockTime)
+	if !anyoneCanPay {
+		w.Write(sh.shaPrevOuts[:])
+		w.Write(sh.shaAmounts[:])
+		w.Write(sh.shaScriptPubKeys[:])
+		w.Write(sh.shaSequences[:])
+	}
+	if outputType == SigHashAll {
+		w.Write(sh.shaOutputs[:])
+	}
+	var spendType byte
+	if leafHash != nil {
+		spendType |= 2
+	}
+	if annex != nil {
+		spendType |= 1
+	}
+	w.WriteByte(spendType)
+	if anyoneCanPay {
+		in := &tx.In[inIndx]
+		w.Write(in.PrevTx[:])
+		binary.Write(w, binary.LittleEndian, in.PrevTxOutIndx)
+		w.Write(sh.spentOuts[inIndx].Raw())
+		binary.Write(w, binary.LittleEndian, in.SequenceNum)
+	} else {
+		binary.Write(w, binary.LittleEndian, uint32(inIndx))
+	}
+	if annex != nil {
+		a := new(bytes.Buffer)
+		WriteVarint(a, Varint(len(annex)))
+		a.Write(annex)
+		sum := sha256.Sum256(a.Bytes())
+		w.Write(sum[:])
+	}
+	if outputType == SigHashSingle {
+		sum := sha256.Sum256(tx.Out[inIndx].Raw())
+		w.Write(sum[:])
+	}
+	if leafHash != nil {
+		w.Write(leafHash)
+		// key version
+		w.WriteByte(0)
+		binary.Write(w, binary.LittleEndian, codeSepPos)
+	}
+	return taggedHash("TapSighash", w.Bytes()), nil
+}
@@ -0,0 +1,389 @@
+//
+// taproot_test.go
+// Copyright (C) 2017 weirdgiraffe <giraffe@cyberzoo.xyz>
+//
+// Distributed under terms of the MIT license.
+//
+
+package bitcoin
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+)
+
+const taprootFlags = ScriptVerifyP2SH | ScriptVerifyWitness | ScriptVerifyTaproot
+
+// case is taken from bip-0086 test vectors
+func TestTaprootOutputKey(t *testing.T) {
+	key, _, err := TaprootOutputKey(hex2byte("cc8a4bc64d897bddc5fbc2f670f7a8ba0b386779106cf1223c6fc5d7cd6fc115"), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := hex2byte("a60869f0dbcf1dc659c9cecbaf8050135ea9e8cdc487053f1dc6880949dc684c")
+	if !bytes.Equal(key, expected) {
+		t.Errorf("expected %x, got %x", expected, key)
+	}
+}
+
+func testPrivKey(seed string) *btcec.PrivateKey {
+	h := sha256.Sum256([]byte(seed))
+	priv, _ := btcec.PrivKeyFromBytes(h[:])
+	return priv
+}
+
+// testTweakPrivKey return private key of taproot output key which
+// commits to merkleRoot
+func testTweakPrivKey(priv *btcec.PrivateKey, merkleRoot []byte) *btcec.PrivateKey {
+	d := priv.Key
+	if priv.PubKey().SerializeCompressed()[0] == 0x03 {
+		d.Negate()
+	}
+	th := taggedHash("TapTweak", schnorr.SerializePubKey(priv.PubKey()), merkleRoot)
+	var tweak btcec.ModNScalar
+	tweak.SetBytes(&th)
+	d.Add(&tweak)
+	b := d.Bytes()
+	tweaked, _ := btcec.PrivKeyFromBytes(b[:])
+	return tweaked
+}
+
+// testTaprootTx return transaction spending a single taproot output
+// with output key and fetcher for the spent output
+func testTaprootTx(outputKey []byte) (*Tx, PrevOutMap) {
+	tx := &Tx{
+		Version: 2,
+		In: []TxIn{
+			{PrevTx: sha256.Sum256([]byte("prev")), PrevTxOutIndx: 1, SequenceNum: 0xffffffff},
+		},
+		Out: []TxOut{
+			{Value: 90000, Script: hex2byte("0014751e76e8199196d454941c45d1b3a323f1433bd6")},
+		},
+	}
+	script := append([]byte{OP_1, 0x20}, outputKey...)
+	prevOuts := PrevOutMap{
+		OutPoint{tx.In[0].PrevTx, 1}: &TxOut{Value: 100000, Script: script},
+	}
+	return tx, prevOuts
+}
+
+func testSchnorrSign(t *testing.T, priv *btcec.PrivateKey, h [32]byte) []byte {
+	sig, err := schnorr.Sign(priv, h[:])
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	return sig.Serialize()
+}
+
+func TestVerifyTaprootKeyPath(t *testing.T) {
+	priv := testPrivKey("internal")
+	outputKey, _, err := TaprootOutputKey(schnorr.SerializePubKey(priv.PubKey()), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tweaked := testTweakPrivKey(priv, nil)
+	tt := []struct {
+		hashType   SigHashType
+		suffix     []byte
+		annex      []byte
+		expect_err bool
+	}{
+		{SigHashDefault, nil, nil, false},
+		{SigHashAll, []byte{0x01}, nil, false},
+		{SigHashSingle | SigHashAnyOneCanPay, []byte{0x83}, nil, false},
+		{SigHashNone, []byte{0x02}, []byte{0x50, 0x01}, false},
+		// explicit SIGHASH_DEFAULT is not allowed
+		{SigHashDefault, []byte{0x00}, nil, true},
+		{0x04, []byte{0x04}, nil, true},
+		// signature commits to the sighash type
+		{SigHashAll, []byte{0x02}, nil, true},
+	}
+	for i := range tt {
+		tx, prevOuts := testTaprootTx(outputKey)
+		spentOuts := []*TxOut{prevOuts[OutPoint{tx.In[0].PrevTx, 1}]}
+		h, err := tx.TaprootSignatureHash(0, spentOuts, tt[i].hashType, tt[i].annex, nil, 0xffffffff)
+		if err != nil {
+			if !tt[i].expect_err {
+				t.Errorf("case #%d unexpected sighash error: %v", i+1, err)
+			}
+			continue
+		}
+		sig := append(testSchnorrSign(t, tweaked, h), tt[i].suffix...)
+		tx.In[0].Witness = [][]byte{sig}
+		if tt[i].annex != nil {
+			tx.In[0].Witness = append(tx.In[0].Witness, tt[i].annex)
+		}
+		err = VerifyInput(tx, 0, taprootFlags, prevOuts)
+		if tt[i].expect_err {
+			if err == nil {
+				t.Errorf("case #%d expected to fail", i+1)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("case #%d error: %v", i+1, err)
+			continue
+		}
+		// amount is covered by the signature
+		prevOuts[OutPoint{tx.In[0].PrevTx, 1}].Value++
+		err = VerifyInput(tx, 0, taprootFlags, prevOuts)
+		if err == nil {
+			t.Errorf("case #%d expected to fail for modified amount", i+1)
+		}
+		// without taproot rules witness v1 is anyone-can-spend
+		tx.In[0].Witness = [][]byte{{}}
+		err = VerifyInput(tx, 0, ScriptVerifyP2SH|ScriptVerifyWitness, prevOuts)
+		if err != nil {
+			t.Errorf("case #%d error without taproot flag: %v", i+1, err)
+		}
+		// taproot output is not an upgradable witness program
+		err = VerifyInput(tx, 0, ScriptVerifyP2SH|ScriptVerifyWitness|ScriptVerifyDiscourageUpgradableWitnessProgram, prevOuts)
+		if err != nil {
+			t.Errorf("case #%d error without taproot flag: %v", i+1, err)
+		}
+	}
+}
+
+func TestVerifyTaprootScriptPath(t *testing.T) {
+	internal := testPrivKey("internal")
+	k1 := testPrivKey("key1")
+	k2 := testPrivKey("key2")
+	x1 := schnorr.SerializePubKey(k1.PubKey())
+	x2 := schnorr.SerializePubKey(k2.PubKey())
+
+	// <x1> OP_CHECKSIG
+	checkSig := append(append([]byte{0x20}, x1...), OP_CHECKSIG)
+	// <x1> OP_CHECKSIG <x2> OP_CHECKSIGADD OP_2 OP_NUMEQUAL
+	checkSigAdd := append(append([]byte{0x20}, x1...), OP_CHECKSIG, 0x20)
+	checkSigAdd = append(append(checkSigAdd, x2...), OP_CHECKSIGADD, OP_2, OP_NUMEQUAL)
+	// OP_0 OP_0 OP_0 OP_CHECKMULTISIG
+	multiSig := []byte{OP_0, OP_0, OP_0, OP_CHECKMULTISIG}
+	// OP_RETURN OP_SUCCESS80
+	success := []byte{OP_RETURN, 0x50}
+	// OP_RETURN with unknown leaf version
+	unknown := []byte{OP_RETURN}
+
+	leaves := []struct {
+		version byte
+		script  []byte
+	}{
+		{TapLeafTapscript, checkSig},
+		{TapLeafTapscript, checkSigAdd},
+		{TapLeafTapscript, multiSig},
+		{TapLeafTapscript, success},
+		{0xc2, unknown},
+	}
+	// tree is built as ((0, 1), (2, (3, 4)))
+	var lh [5][32]byte
+	for i := range leaves {
+		lh[i] = TapLeafHash(leaves[i].version, leaves[i].script)
+	}
+	b01 := TapBranchHash(lh[0][:], lh[1][:])
+	b34 := TapBranchHash(lh[3][:], lh[4][:])
+	b234 := TapBranchHash(lh[2][:], b34[:])
+	root := TapBranchHash(b01[:], b234[:])
+	paths := [][][]byte{
+		{lh[1][:], b234[:]},
+		{lh[0][:], b234[:]},
+		{b34[:], b01[:]},
+		{lh[4][:], lh[2][:], b01[:]},
+		{lh[3][:], lh[2][:], b01[:]},
+	}
+	internalKey := schnorr.SerializePubKey(internal.PubKey())
+	outputKey, odd, err := TaprootOutputKey(internalKey, root[:])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	control := func(leaf int) []byte {
+		c := []byte{leaves[leaf].version}
+		if odd {
+			c[0] |= 1
+		}
+		c = append(c, internalKey...)
+		for _, node := range paths[leaf] {
+			c = append(c, node...)
+		}
+		return c
+	}
+
+	const (
+		sig1 = iota
+		sig2
+		badSig
+		empty
+	)
+	tt := []struct {
+		leaf       int
+		stack      []int
+		annex      []byte
+		control    func([]byte) []byte
+		expect_err bool
+	}{
+		{0, []int{sig1}, nil, nil, false},
+		{0, []int{sig1}, []byte{0x50}, nil, false},
+		{0, []int{empty}, nil, nil, true},
+		{0, []int{badSig}, nil, nil, true},
+		{0, []int{sig2}, nil, nil, true},
+		{1, []int{sig2, sig1}, nil, nil, false},
+		{1, []int{sig2, empty}, nil, nil, true},
+		{1, []int{empty, sig1}, nil, nil, true},
+		{2, []int{}, nil, nil, true},
+		{3, []int{empty, badSig}, nil, nil, false},
+		{4, []int{}, nil, nil, false},
+		// wrong output key parity
+		{0, []int{sig1}, nil, func(c []byte) []byte { c[0] ^= 1; return c }, true},
+		// wrong merkle path
+		{0, []int{sig1}, nil, func(c []byte) []byte { return c[:len(c)-32] }, true},
+		// control block has wrong size
+		{0, []int{sig1}, nil, func(c []byte) []byte { return c[:len(c)-1] }, true},
+	}
+	for i := range tt {
+		tx, prevOuts := testTaprootTx(outputKey)
+		spentOuts := []*TxOut{prevOuts[OutPoint{tx.In[0].PrevTx, 1}]}
+		leaf := leaves[tt[i].leaf]
+		h, err := tx.TaprootSignatureHash(0, spentOuts, SigHashDefault, tt[i].annex, lh[tt[i].leaf][:], 0xffffffff)
+		if err != nil {
+			t.Fatalf("case #%d unexpected sighash error: %v", i+1, err)
+		}
+		var witness [][]byte
+		for _, item := range tt[i].stack {
+			switch item {
+			case sig1:
+				witness = append(witness, testSchnorrSign(t, k1, h))
+			case sig2:
+				witness = append(witness, testSchnorrSign(t, k2, h))
+			case badSig:
+				witness = append(witness, make([]byte, 64))
+			case empty:
+				witness = append(witness, []byte{})
+			}
+		}
+		c := control(tt[i].leaf)
+		if tt[i].control != nil {
+			c = tt[i].control(c)
+		}
+		witness = append(witness, leaf.script, c)
+		if tt[i].annex != nil {
+			witness = append(witness, tt[i].annex)
+		}
+		tx.In[0].Witness = witness
+		err = VerifyInput(tx, 0, taprootFlags, prevOuts)
+		if tt[i].expect_err {
+			if err == nil {
+				t.Errorf("case #%d expected to fail", i+1)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("case #%d error: %v", i+1, err)
+		}
+	}
+}
+
+func TestVerifyTaprootInvalidKeys(t *testing.T) {
+	invalidKey := bytes.Repeat([]byte{0xff}, 32)
+	// key path spending of output with key which is not on the curve
+	tx, prevOuts := testTaprootTx(invalidKey)
+	tx.In[0].Witness = [][]byte{bytes.Repeat([]byte{0x01}, 64)}
+	err := VerifyInput(tx, 0, taprootFlags, prevOuts)
+	if !errors.Is(err, ErrSchnorrSig) {
+		t.Errorf("expected SCHNORR_SIG, got %v", err)
+	}
+	// script path spending with invalid internal key
+	priv := testPrivKey("internal")
+	outputKey, _, err := TaprootOutputKey(schnorr.SerializePubKey(priv.PubKey()), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tx, prevOuts = testTaprootTx(outputKey)
+	control := append([]byte{TapLeafTapscript}, invalidKey...)
+	tx.In[0].Witness = [][]byte{{OP_1}, control}
+	err = VerifyInput(tx, 0, taprootFlags, prevOuts)
+	if !errors.Is(err, ErrWitnessProgramMismatch) {
+		t.Errorf("expected WITNESS_PROGRAM_MISMATCH, got %v", err)
+	}
+}
+
+func TestTaprootSignatureHashArgs(t *testing.T) {
+	tx, prevOuts := testTaprootTx(bytes.Repeat([]byte{0x01}, 32))
+	out := prevOuts[OutPoint{tx.In[0].PrevTx, 1}]
+	tt := []struct {
+		inIndx    int
+		spentOuts []*TxOut
+	}{
+		{-1, []*TxOut{out}},
+		{1, []*TxOut{out}},
+		{0, nil},
+		{0, []*TxOut{out, out}},
+		{0, []*TxOut{nil}},
+	}
+	for i := range tt {
+		_, err := tx.TaprootSignatureHash(tt[i].inIndx, tt[i].spentOuts, SigHashDefault, nil, nil, 0xffffffff)
+		if err == nil {
+			t.Errorf("case #%d expected to fail", i+1)
+		}
+	}
+	if _, err := tx.TaprootSignatureHash(0, []*TxOut{out}, SigHashDefault, nil, nil, 0xffffffff); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+// countingPrevOuts counts lookups of spent outputs
+type countingPrevOuts struct {
+	PrevOutMap
+	count int
+}
+
+func (p *countingPrevOuts) FetchPrevOut(hash DoubleHash, indx uint32) (*TxOut, error) {
+	p.count++
+	return p.PrevOutMap.FetchPrevOut(hash, indx)
+}
+
+func TestVerifyTxSharesSigHashes(t *testing.T) {
+	priv := testPrivKey("internal")
+	outputKey, _, err := TaprootOutputKey(schnorr.SerializePubKey(priv.PubKey()), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tweaked := testTweakPrivKey(priv, nil)
+	tx, prevOuts := testTaprootTx(outputKey)
+	script := prevOuts[OutPoint{tx.In[0].PrevTx, 1}].Script
+	for i := 1; i < 3; i++ {
+		in := TxIn{PrevTx: DoubleHash{byte(i)}, SequenceNum: 0xffffffff}
+		tx.In = append(tx.In, in)
+		prevOuts[OutPoint{in.PrevTx, 0}] = &TxOut{Value: 1000, Script: script}
+	}
+	spentOuts := make([]*TxOut, len(tx.In))
+	for i := range tx.In {
+		spentOuts[i], _ = prevOuts.FetchPrevOut(tx.In[i].PrevTx, tx.In[i].PrevTxOutIndx)
+	}
+	sigHashes := NewTxSigHashes(tx, spentOuts)
+	for i := range tx.In {
+		h, err := sigHashes.TaprootSignatureHash(tx, i, SigHashDefault, nil, nil, 0xffffffff)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		expected, err := tx.TaprootSignatureHash(i, spentOuts, SigHashDefault, nil, nil, 0xffffffff)
+		if err != nil || h != expected {
+			t.Fatalf("input %d shared hash mismatch: %v", i, err)
+		}
+		tx.In[i].Witness = [][]byte{testSchnorrSign(t, tweaked, h)}
+	}
+	fetcher := &countingPrevOuts{PrevOutMap: prevOuts}
+	if err = VerifyTx(tx, taprootFlags, fetcher); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fetcher.count != len(tx.In) {
+		t.Errorf("expected %d prevout lookups, got %d", len(tx.In), fetcher.count)
+	}
+	// signature of the last input commits to amounts of all inputs
+	spentOuts[0].Value++
+	if err = VerifyTx(tx, taprootFlags, prevOuts); !errors.Is(err, ErrSchnorrSig) {
+		t.Errorf("expected SCHNORR_SIG, got %v", err)
+	}
+}
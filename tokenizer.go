@@ -0,0 +1,136 @@
+//
+// tokenizer.go
+// Copyright (C) 2017 weirdgiraffe <giraffe@cyberzoo.xyz>
+//
+// Distributed under terms of the MIT license.
+//
+
+package bitcoin
+
+import (
+	"encoding/binary"
+)
+
+// ScriptTokenizer iterates over operations of a script:
+//
+//	t := NewScriptTokenizer(script)
+//	for t.Next() {
+//		op, data := t.Opcode(), t.Data()
+//		...
+//	}
+//	if t.Err() != nil {
+//		// script is truncated
+//	}
+type ScriptTokenizer struct {
+	script []byte
+	offset int
+	end    int
+	op     byte
+	data   []byte
+	err    error
+}
+
+// NewScriptTokenizer creates tokenizer positioned before the first
+// operation of script
+func NewScriptTokenizer(script []byte) *ScriptTokenizer {
+	return &ScriptTokenizer{script: script}
+}
+
+// Next moves to the next operation. It return false when there are no
+// more operations or the next operation is truncated, which is reported
+// by Err
+func (t *ScriptTokenizer) Next() bool {
+	if t.err != nil || t.end >= len(t.script) {
+		return false
+	}
+	t.offset = t.end
+	t.op = t.script[t.offset]
+	n, ok := pushDataLen(t.op, t.script[t.offset+1:])
+	if !ok {
+		t.data = nil
+		t.err = withOffset(scriptError(ErrBadOpcode, "%s is truncated", OpcodeName(t.op)), t.offset)
+		return false
+	}
+	t.data = nil
+	if t.op <= OP_PUSHDATA4 {
+		t.data = t.script[t.offset+1+pushDataPrefixLen(t.op) : t.offset+1+n]
+	}
+	t.end = t.offset + 1 + n
+	return true
+}
+
+// Opcode return opcode of the current operation
+func (t *ScriptTokenizer) Opcode() byte {
+	return t.op
+}
+
+// Data return data pushed by the current operation or nil if it is not
+// a push operation. Small integer operations like OP_1 do not have data
+func (t *ScriptTokenizer) Data() []byte {
+	return t.data
+}
+
+// Offset return offset of the current operation in the script. After
+// failure it is the offset of the truncated operation
+func (t *ScriptTokenizer) Offset() int {
+	return t.offset
+}
+
+// End return offset right after the current operation
+func (t *ScriptTokenizer) End() int {
+	return t.end
+}
+
+// Done return true if all operations were read without errors
+func (t *ScriptTokenizer) Done() bool {
+	return t.err == nil && t.end >= len(t.script)
+}
+
+// Err return ScriptError with ErrBadOpcode code if script is truncated
+func (t *ScriptTokenizer) Err() error {
+	return t.err
+}
+
+// pushDataPrefixLen return size of data length which follows push
+// operation op
+func pushDataPrefixLen(op byte) int {
+	switch op {
+	case OP_PUSHDATA1:
+		return 1
+	case OP_PUSHDATA2:
+		return 2
+	case OP_PUSHDATA4:
+		return 4
+	}
+	return 0
+}
+
+// pushDataLen return number of bytes following op in script that are
+// consumed by the push operation. ok is false if script is truncated
+func pushDataLen(op byte, script []byte) (n int, ok bool) {
+	switch {
+	case op < OP_PUSHDATA1:
+		n = int(op)
+	case op == OP_PUSHDATA1:
+		if len(script) < 1 {
+			return 0, false
+		}
+		n = int(script[0]) + 1
+	case op == OP_PUSHDATA2:
+		if len(script) < 2 {
+			return 0, false
+		}
+		n = int(binary.LittleEndian.Uint16(script)) + 2
+	case op == OP_PUSHDATA4:
+		if len(script) < 4 {
+			return 0, false
+		}
+		// compare before conversion, which could overflow 32-bit int
+		l := uint64(binary.LittleEndian.Uint32(script)) + 4
+		if l > uint64(len(script)) {
+			return 0, false
+		}
+		n = int(l)
+	}
+	return n, n <= len(script)
+}
@@ -0,0 +1,94 @@
+//
+// tokenizer_test.go
+// Copyright (C) 2017 weirdgiraffe <giraffe@cyberzoo.xyz>
+//
+// Distributed under terms of the MIT license.
+//
+
+package bitcoin
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestScriptTokenizer(t *testing.T) {
+	type token struct {
+		op     byte
+		data   string
+		offset int
+	}
+	tt := []struct {
+		script     string
+		tokens     []token
+		expect_err bool
+		errOffset  int
+	}{
+		{"", nil, false, 0},
+		{
+			"76a9145b6462475454710f3c22f5fdf0b40704c92f25c388ac",
+			[]token{
+				{OP_DUP, "", 0},
+				{OP_HASH160, "", 1},
+				{0x14, "5b6462475454710f3c22f5fdf0b40704c92f25c3", 2},
+				{OP_EQUALVERIFY, "", 23},
+				{OP_CHECKSIG, "", 24},
+			},
+			false, 0,
+		},
+		{
+			"004c00" + "4c01aa" + "4d0200aabb" + "4e01000000cc" + "51",
+			[]token{
+				{OP_0, "", 0},
+				{OP_PUSHDATA1, "", 1},
+				{OP_PUSHDATA1, "aa", 3},
+				{OP_PUSHDATA2, "aabb", 6},
+				{OP_PUSHDATA4, "cc", 11},
+				{OP_1, "", 17},
+			},
+			false, 0,
+		},
+		{"5102aa", []token{{OP_1, "", 0}}, true, 1},
+		{"514c", []token{{OP_1, "", 0}}, true, 1},
+		{"4d01", nil, true, 0},
+		{"4d0100", nil, true, 0},
+		{"4e010000", nil, true, 0},
+		// lengths which overflow 32-bit int
+		{"4efcffffffaa", nil, true, 0},
+		{"4effffffffaa", nil, true, 0},
+	}
+	for i := range tt {
+		tok := NewScriptTokenizer(hex2byte(tt[i].script))
+		n := 0
+		for tok.Next() {
+			if n >= len(tt[i].tokens) {
+				t.Errorf("case #%d unexpected token 0x%02x", i+1, tok.Opcode())
+				break
+			}
+			e := tt[i].tokens[n]
+			if tok.Opcode() != e.op || !bytes.Equal(tok.Data(), hex2byte(e.data)) || tok.Offset() != e.offset {
+				t.Errorf("case #%d token %d expected %s %s at %d, got %s %x at %d",
+					i+1, n, OpcodeName(e.op), e.data, e.offset,
+					OpcodeName(tok.Opcode()), tok.Data(), tok.Offset())
+			}
+			n++
+		}
+		if n != len(tt[i].tokens) {
+			t.Errorf("case #%d expected %d tokens, got %d", i+1, len(tt[i].tokens), n)
+		}
+		err := tok.Err()
+		if err != nil && tt[i].expect_err == false {
+			t.Errorf("case #%d error: %v", i+1, err)
+		}
+		if tt[i].expect_err {
+			var se *ScriptError
+			if !errors.As(err, &se) || se.Code != ErrBadOpcode || se.Offset != tt[i].errOffset {
+				t.Errorf("case #%d expected %s at offset %d, got %v", i+1, ErrBadOpcode, tt[i].errOffset, err)
+			}
+		}
+		if tok.Done() == tt[i].expect_err {
+			t.Errorf("case #%d unexpected Done %v", i+1, tok.Done())
+		}
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"bytes"
 	"encoding/binary"
 	"encoding/json"
+	"fmt"
 	"io"
 )
 
@@ -19,6 +20,8 @@ type TxIn struct {
 	PrevTxOutIndx uint32
 	Script        []byte
 	SequenceNum   uint32
+	// Witness is a segregated witness stack of input (BIP144)
+	Witness [][]byte
 }
 
 type TxOut struct {
@@ -32,20 +35,36 @@ type Tx struct {
 	Out      []TxOut
 	LockTime uint32
 
-	Hash  DoubleHash
-	Block DoubleHash
+	Hash        DoubleHash
+	WitnessHash DoubleHash
+	Block       DoubleHash
+}
+
+// HasWitness return true if any of transaction inputs has witness data
+func (t Tx) HasWitness() bool {
+	for i := range t.In {
+		if len(t.In[i].Witness) != 0 {
+			return true
+		}
+	}
+	return false
 }
 
 // IsCoinbase return true if this transaction is a generation transaction
 // i.e. input for this transaction is a new generated block
 func (t Tx) IsCoinbase() bool {
-	null := DoubleHash{}
-	return len(t.In) == 1 && bytes.Compare(null[:], t.In[0].PrevTx[:]) == 0
+	return len(t.In) == 1 && t.In[0].HasNullPrevOut()
+}
+
+// HasNullPrevOut return true if input refers to the null output, which
+// has zero hash and index 0xffffffff. Only coinbase input spends it
+func (t *TxIn) HasNullPrevOut() bool {
+	return t.PrevTx == DoubleHash{} && t.PrevTxOutIndx == 0xffffffff
 }
 
 func ReadTxIn(r io.Reader) (t *TxIn, err error) {
 	t = new(TxIn)
-	_, err = r.Read(t.PrevTx[:])
+	_, err = io.ReadFull(r, t.PrevTx[:])
 	if err != nil {
 		return
 	}
@@ -53,13 +72,7 @@ func ReadTxIn(r io.Reader) (t *TxIn, err error) {
 	if err != nil {
 		return
 	}
-	var n Varint
-	err = ReadVarint(r, &n)
-	if err != nil {
-		return
-	}
-	t.Script = make([]byte, int(n))
-	_, err = r.Read(t.Script)
+	t.Script, err = readVarBytes(r)
 	if err != nil {
 		return
 	}
@@ -76,30 +89,43 @@ func ReadTxOut(r io.Reader) (t *TxOut, err error) {
 	if err != nil {
 		return
 	}
-	var n Varint
-	err = ReadVarint(r, &n)
-	if err != nil {
-		return
-	}
-	t.Script = make([]byte, int(n))
-	_, err = r.Read(t.Script)
+	t.Script, err = readVarBytes(r)
 	if err != nil {
 		return
 	}
 	return t, nil
 }
 
+// ReadTx reads transaction in both original and segregated witness
+// (BIP144) serialization formats
 func ReadTx(r io.Reader) (t *Tx, err error) {
 	t = new(Tx)
 	err = binary.Read(r, binary.LittleEndian, &t.Version)
 	if err != nil {
 		return
 	}
-	var count Varint
-	err = ReadVarint(r, &count)
+	count, err := readCount(r)
 	if err != nil {
 		return
 	}
+	// zero inputs count is a marker of witness serialization and is
+	// followed by flag and the actual inputs count
+	witness := false
+	if count == 0 {
+		var flag [1]byte
+		_, err = io.ReadFull(r, flag[:])
+		if err != nil {
+			return
+		}
+		if flag[0] != 0x01 {
+			return nil, fmt.Errorf("Unknown transaction serialization flag 0x%02x", flag[0])
+		}
+		witness = true
+		count, err = readCount(r)
+		if err != nil {
+			return
+		}
+	}
 	for i := Varint(0); i < count; i++ {
 		var in *TxIn
 		in, err = ReadTxIn(r)
@@ -108,7 +134,7 @@ func ReadTx(r io.Reader) (t *Tx, err error) {
 		}
 		t.In = append(t.In, *in)
 	}
-	err = ReadVarint(r, &count)
+	count, err = readCount(r)
 	if err != nil {
 		return
 	}
@@ -120,20 +146,104 @@ func ReadTx(r io.Reader) (t *Tx, err error) {
 		}
 		t.Out = append(t.Out, *out)
 	}
+	if witness {
+		for i := range t.In {
+			t.In[i].Witness, err = ReadWitness(r)
+			if err != nil {
+				return
+			}
+		}
+		if !t.HasWitness() {
+			return nil, fmt.Errorf("Transaction has witness flag but no witness data")
+		}
+	}
 	err = binary.Read(r, binary.LittleEndian, &t.LockTime)
 	if err != nil {
 		return
 	}
 	t.Hash.Update(t.Raw())
+	if witness {
+		t.WitnessHash.Update(t.RawWitness())
+	} else {
+		t.WitnessHash = t.Hash
+	}
 	return t, nil
 }
 
+// ReadWitness reads witness stack of a single transaction input
+func ReadWitness(r io.Reader) (w [][]byte, err error) {
+	count, err := readCount(r)
+	if err != nil {
+		return
+	}
+	for i := Varint(0); i < count; i++ {
+		var item []byte
+		item, err = readVarBytes(r)
+		if err != nil {
+			return
+		}
+		w = append(w, item)
+	}
+	return w, nil
+}
+
+// maxReadSize limits lengths and counts read from untrusted data before
+// anything is allocated for them. No transaction part could be bigger
+// than the maximum block weight
+const maxReadSize = MaxBlockWeight
+
+// readCount reads varint count or length, which is limited by maxReadSize
+func readCount(r io.Reader) (Varint, error) {
+	var n Varint
+	err := ReadVarint(r, &n)
+	if err != nil {
+		return 0, err
+	}
+	if n > maxReadSize {
+		return 0, fmt.Errorf("Length %d exceeds %d", n, maxReadSize)
+	}
+	return n, nil
+}
+
+// readVarBytes reads byte string prefixed with its varint length
+func readVarBytes(r io.Reader) ([]byte, error) {
+	n, err := readCount(r)
+	if err != nil {
+		return nil, err
+	}
+	b := make([]byte, int(n))
+	_, err = io.ReadFull(r, b)
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Raw return transaction serialization without witness data, which is
+// used to compute transaction id
 func (tx *Tx) Raw() []byte {
+	return tx.serialize(false)
+}
+
+// RawWitness return transaction serialization with witness data (BIP144),
+// which is used to compute witness transaction id. For transactions
+// without witness it is the same as Raw()
+func (tx *Tx) RawWitness() []byte {
+	return tx.serialize(tx.HasWitness())
+}
+
+func (tx *Tx) serialize(witness bool) []byte {
 	w := new(bytes.Buffer)
 	err := binary.Write(w, binary.LittleEndian, tx.Version)
 	if err != nil {
 		panic(err)
 	}
+	if witness {
+		_, err = w.Write([]byte{0x00, 0x01})
+		if err != nil {
+			panic(err)
+		}
+	}
 	inCount := Varint(len(tx.In))
 	err = WriteVarint(w, inCount)
 	if err != nil {
@@ -156,6 +266,14 @@ func (tx *Tx) Raw() []byte {
 			panic(err)
 		}
 	}
+	if witness {
+		for i := range tx.In {
+			err = WriteWitness(w, tx.In[i].Witness)
+			if err != nil {
+				panic(err)
+			}
+		}
+	}
 	err = binary.Write(w, binary.LittleEndian, tx.LockTime)
 	if err != nil {
 		panic(err)
@@ -163,6 +281,25 @@ func (tx *Tx) Raw() []byte {
 	return w.Bytes()
 }
 
+// WriteWitness writes witness stack of a single transaction input
+func WriteWitness(w io.Writer, witness [][]byte) (err error) {
+	err = WriteVarint(w, Varint(len(witness)))
+	if err != nil {
+		return
+	}
+	for i := range witness {
+		err = WriteVarint(w, Varint(len(witness[i])))
+		if err != nil {
+			return
+		}
+		_, err = w.Write(witness[i])
+		if err != nil {
+			return
+		}
+	}
+	return nil
+}
+
 func (tx *TxIn) Raw() []byte {
 	w := new(bytes.Buffer)
 	_, err := w.Write(tx.PrevTx[:])
@@ -182,7 +319,7 @@ func (tx *TxIn) Raw() []byte {
 	if err != nil {
 		panic(err)
 	}
-	err = binary.Write(w, binary.LittleEndian, tx.PrevTxOutIndx)
+	err = binary.Write(w, binary.LittleEndian, tx.SequenceNum)
 	if err != nil {
 		panic(err)
 	}
@@ -207,6 +344,24 @@ func (tx *TxOut) Raw() []byte {
 	return w.Bytes()
 }
 
+// MarshalJSON adds disassembled script to JSON representation of input
+func (tx TxIn) MarshalJSON() ([]byte, error) {
+	type txIn TxIn
+	return json.Marshal(struct {
+		txIn
+		ScriptASM string
+	}{txIn(tx), Disassemble(tx.Script, true)})
+}
+
+// MarshalJSON adds disassembled script to JSON representation of output
+func (tx TxOut) MarshalJSON() ([]byte, error) {
+	type txOut TxOut
+	return json.Marshal(struct {
+		txOut
+		ScriptASM string
+	}{txOut(tx), Disassemble(tx.Script, false)})
+}
+
 func (tx TxIn) String() string {
 	ob, err := json.MarshalIndent(&tx, "", "  ")
 	if err != nil {
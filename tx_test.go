@@ -10,6 +10,7 @@ package bitcoin
 import (
 	"bytes"
 	"encoding/hex"
+	"strings"
 	"testing"
 )
 
@@ -41,3 +42,63 @@ func TestTxSerialisation(t *testing.T) {
 		t.Errorf("serialized tx not match input tx:\ninput:\n%s\nserialized:\n%s", hex.Dump(rawTx), hex.Dump(b))
 	}
 }
+
+// segnet transaction with a single P2WPKH input of 0.004 BTC
+const testP2WPKHTx = "01000000000101a53352d5135766f03076597418263da2d9c958315968fea823529467481ff9cd" +
+	"1300000000ffffffff010b070600000000001600149ddac6f39d51e0398e532a22c41ba189406a8523" +
+	"0246304302" + "1f4d2381dc97f182abd8185f51753018523212f5ddc07cc4e63a8dc03658da19" +
+	"0220608b5c4d92b86b6de7d78ef23a2fa735bcb59b914a48b0e187c5e7569a18197001" +
+	"210307ead084807eb76346df6977000c89392f45c76425b26181f521d7f370066a8f00000000"
+
+func TestWitnessTxSerialisation(t *testing.T) {
+	rawTx := hex2byte(testP2WPKHTx)
+	tx, err := ReadTx(bytes.NewBuffer(rawTx))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !tx.HasWitness() || len(tx.In[0].Witness) != 2 {
+		t.Fatalf("witness is not parsed: %v", tx.In[0].Witness)
+	}
+	if len(tx.In[0].Witness[0]) != 70 || len(tx.In[0].Witness[1]) != 33 {
+		t.Errorf("witness items length mismatch")
+	}
+	b := tx.RawWitness()
+	if bytes.Compare(rawTx, b) != 0 {
+		t.Errorf("serialized tx not match input tx:\ninput:\n%s\nserialized:\n%s", hex.Dump(rawTx), hex.Dump(b))
+	}
+	txid := mustParseHash("0f167d1385a84d1518cfee208b653fc9163b605ccf1b75347e2850b3e2eb19f3")
+	if tx.Hash != txid {
+		t.Errorf("txid mismatch %x != %x", txid, tx.Hash)
+	}
+	wtxid := mustParseHash("0858eab78e77b6b033da30f46699996396cf48fcf625a783c85a51403e175e74")
+	if tx.WitnessHash != wtxid {
+		t.Errorf("wtxid mismatch %x != %x", wtxid, tx.WitnessHash)
+	}
+	stripped, err := ReadTx(bytes.NewBuffer(tx.Raw()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stripped.HasWitness() || stripped.Hash != txid || stripped.WitnessHash != txid {
+		t.Errorf("stripped tx mismatch")
+	}
+}
+
+func TestReadHugeLengths(t *testing.T) {
+	outPoint := strings.Repeat("00", 36)
+	tt := []struct {
+		read func([]byte) error
+		data string
+	}{
+		{func(b []byte) error { _, err := ReadWitness(bytes.NewReader(b)); return err }, "01ffffffffffffffff7f"},
+		{func(b []byte) error { _, err := ReadWitness(bytes.NewReader(b)); return err }, "fe01093d00"},
+		{func(b []byte) error { _, err := ReadWitness(bytes.NewReader(b)); return err }, "01feffffff7f"},
+		{func(b []byte) error { _, err := ReadTxIn(bytes.NewReader(b)); return err }, outPoint + "ffffffffffffffffff"},
+		{func(b []byte) error { _, err := ReadTxOut(bytes.NewReader(b)); return err }, "0000000000000000feffffff7f"},
+		{func(b []byte) error { _, err := ReadTx(bytes.NewReader(b)); return err }, "01000000ffffffffffffffff7f"},
+	}
+	for i := range tt {
+		if err := tt[i].read(hex2byte(tt[i].data)); err == nil {
+			t.Errorf("case #%d expected to fail", i)
+		}
+	}
+}
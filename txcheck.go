@@ -0,0 +1,138 @@
+//
+// txcheck.go
+// Copyright (C) 2017 weirdgiraffe <giraffe@cyberzoo.xyz>
+//
+// Distributed under terms of the MIT license.
+//
+
+package bitcoin
+
+import (
+	"fmt"
+)
+
+const (
+	// MaxMoney is the maximum number of satoshis which may ever exist
+	MaxMoney = 21000000 * Coin
+	// MaxBlockWeight is the maximum weight of block (BIP141)
+	MaxBlockWeight = 4000000
+)
+
+// TxErrorCode identifies the context free transaction rule which is
+// violated. Names are reject reasons of bitcoin core
+type TxErrorCode int
+
+const (
+	TxErrVinEmpty TxErrorCode = iota + 1
+	TxErrVoutEmpty
+	TxErrOversize
+	TxErrVoutNegative
+	TxErrVoutTooLarge
+	TxErrTxOutTotalTooLarge
+	TxErrInputsDuplicate
+	TxErrCoinbaseLength
+	TxErrPrevOutNull
+	TxErrNonFinal
+	TxErrNonBIP68Final
+	TxErrInputValuesOutOfRange
+)
+
+var txErrorCodeNames = map[TxErrorCode]string{
+	TxErrVinEmpty:              "bad-txns-vin-empty",
+	TxErrVoutEmpty:             "bad-txns-vout-empty",
+	TxErrOversize:              "bad-txns-oversize",
+	TxErrVoutNegative:          "bad-txns-vout-negative",
+	TxErrVoutTooLarge:          "bad-txns-vout-toolarge",
+	TxErrTxOutTotalTooLarge:    "bad-txns-txouttotal-toolarge",
+	TxErrInputsDuplicate:       "bad-txns-inputs-duplicate",
+	TxErrCoinbaseLength:        "bad-cb-length",
+	TxErrPrevOutNull:           "bad-txns-prevout-null",
+	TxErrNonFinal:              "bad-txns-nonfinal",
+	TxErrNonBIP68Final:         "non-BIP68-final",
+	TxErrInputValuesOutOfRange: "bad-txns-inputvalues-outofrange",
+}
+
+func (c TxErrorCode) String() string {
+	if name, ok := txErrorCodeNames[c]; ok {
+		return name
+	}
+	return fmt.Sprintf("TxErrorCode(%d)", int(c))
+}
+
+// Error makes codes usable as targets of errors.Is
+func (c TxErrorCode) Error() string {
+	return c.String()
+}
+
+// TxError describes why transaction is invalid
+type TxError struct {
+	Code TxErrorCode
+	// Index of input or output which violates the rule or -1 if rule
+	// applies to the whole transaction
+	Index       int
+	Description string
+}
+
+func (e *TxError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Description)
+}
+
+// Is return true if target is the code of e or a TxError with the same
+// code
+func (e *TxError) Is(target error) bool {
+	switch t := target.(type) {
+	case TxErrorCode:
+		return e.Code == t
+	case *TxError:
+		return e.Code == t.Code
+	}
+	return false
+}
+
+func txError(code TxErrorCode, index int, format string, args ...interface{}) *TxError {
+	return &TxError{
+		Code:        code,
+		Index:       index,
+		Description: fmt.Sprintf(format, args...),
+	}
+}
+
+// CheckTransaction applies the rules which do not depend on the chain
+// state, the same as CheckTransaction of bitcoin core
+//
+// return nil if transaction is valid or *TxError
+func CheckTransaction(tx *Tx) error {
+	if len(tx.In) == 0 {
+		return txError(TxErrVinEmpty, -1, "transaction has no inputs")
+	}
+	if len(tx.Out) == 0 {
+		return txError(TxErrVoutEmpty, -1, "transaction has no outputs")
+	}
+	if tx.BaseSize()*WitnessScaleFactor > MaxBlockWeight {
+		return txError(TxErrOversize, -1, "transaction size %d exceeds block weight", tx.BaseSize())
+	}
+	_, err := tx.OutputValue()
+	if err != nil {
+		return err
+	}
+	spent := make(map[OutPoint]struct{}, len(tx.In))
+	for i := range tx.In {
+		p := OutPoint{tx.In[i].PrevTx, tx.In[i].PrevTxOutIndx}
+		if _, ok := spent[p]; ok {
+			return txError(TxErrInputsDuplicate, i, "input %d spends %s again", i, p)
+		}
+		spent[p] = struct{}{}
+	}
+	if tx.IsCoinbase() {
+		if n := len(tx.In[0].Script); n < 2 || n > 100 {
+			return txError(TxErrCoinbaseLength, 0, "coinbase script length %d is not in range [2, 100]", n)
+		}
+		return nil
+	}
+	for i := range tx.In {
+		if tx.In[i].HasNullPrevOut() {
+			return txError(TxErrPrevOutNull, i, "input %d spends null output", i)
+		}
+	}
+	return nil
+}
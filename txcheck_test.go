@@ -0,0 +1,55 @@
+//
+// txcheck_test.go
+// Copyright (C) 2017 weirdgiraffe <giraffe@cyberzoo.xyz>
+//
+// Distributed under terms of the MIT license.
+//
+
+package bitcoin
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckTransaction(t *testing.T) {
+	in := TxIn{PrevTx: DoubleHash{1}, Script: []byte{OP_1}}
+	null := TxIn{PrevTxOutIndx: 0xffffffff, Script: []byte{OP_1, OP_1}}
+	out := TxOut{Value: Coin, Script: []byte{OP_1}}
+	tt := []struct {
+		in         []TxIn
+		out        []TxOut
+		expect_err error
+	}{
+		{[]TxIn{in}, []TxOut{out}, nil},
+		{nil, []TxOut{out}, TxErrVinEmpty},
+		{[]TxIn{in}, nil, TxErrVoutEmpty},
+		{[]TxIn{{Script: make([]byte, MaxBlockWeight/4)}}, []TxOut{out}, TxErrOversize},
+		{[]TxIn{in}, []TxOut{{Value: 1 << 63}}, TxErrVoutNegative},
+		{[]TxIn{in}, []TxOut{{Value: MaxMoney}}, nil},
+		{[]TxIn{in}, []TxOut{{Value: MaxMoney + 1}}, TxErrVoutTooLarge},
+		{[]TxIn{in}, []TxOut{{Value: MaxMoney}, {Value: 1}}, TxErrTxOutTotalTooLarge},
+		{[]TxIn{in, {PrevTx: DoubleHash{1}, PrevTxOutIndx: 1}}, []TxOut{out}, nil},
+		{[]TxIn{in, {PrevTx: DoubleHash{1}}}, []TxOut{out}, TxErrInputsDuplicate},
+		{[]TxIn{null}, []TxOut{out}, nil},
+		{[]TxIn{{PrevTxOutIndx: 0xffffffff, Script: []byte{OP_1}}}, []TxOut{out}, TxErrCoinbaseLength},
+		{[]TxIn{{PrevTxOutIndx: 0xffffffff, Script: make([]byte, 101)}}, []TxOut{out}, TxErrCoinbaseLength},
+		{[]TxIn{{PrevTxOutIndx: 0xffffffff, Script: make([]byte, 100)}}, []TxOut{out}, nil},
+		{[]TxIn{in, null}, []TxOut{out}, TxErrPrevOutNull},
+		// zero hash with non null index is not a coinbase
+		{[]TxIn{{Script: []byte{OP_1}}}, []TxOut{out}, nil},
+	}
+	for i := range tt {
+		tx := &Tx{Version: 1, In: tt[i].in, Out: tt[i].out}
+		err := CheckTransaction(tx)
+		if tt[i].expect_err == nil {
+			if err != nil {
+				t.Errorf("case #%d: unexpected error: %v", i, err)
+			}
+			continue
+		}
+		if !errors.Is(err, tt[i].expect_err) {
+			t.Errorf("case #%d: expected %v, got %v", i, tt[i].expect_err, err)
+		}
+	}
+}
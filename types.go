@@ -10,16 +10,79 @@ package bitcoin
 import (
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
+	"fmt"
 )
 
+// BadHash is returned when hash string is malformed
+var BadHash = errors.New("Bad hash string")
+
 type UnixTime uint32
+
+// DoubleHash is a double SHA256 hash in internal byte order, the order
+// it is computed and serialized in. Its string form is byte reversed,
+// as shown by block explorers and bitcoin core RPC
 type DoubleHash [32]byte
 
+// NewDoubleHashFromStr parses hash from byte reversed hex string
+func NewDoubleHashFromStr(s string) (h DoubleHash, err error) {
+	if len(s) != 2*len(h) {
+		return h, fmt.Errorf("hash string length %d, expected %d: %w", len(s), 2*len(h), BadHash)
+	}
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return h, fmt.Errorf("%v: %w", err, BadHash)
+	}
+	for i := range b {
+		h[len(h)-1-i] = b[i]
+	}
+	return h, nil
+}
+
+// mustParseHash is NewDoubleHashFromStr for hash constants
+func mustParseHash(s string) DoubleHash {
+	h, err := NewDoubleHashFromStr(s)
+	if err != nil {
+		panic(err)
+	}
+	return h
+}
+
 func (h DoubleHash) MarshalJSON() ([]byte, error) {
 	return []byte("\"" + h.String() + "\""), nil
 }
 
+func (h *DoubleHash) UnmarshalJSON(b []byte) error {
+	if len(b) < 2 || b[0] != '"' || b[len(b)-1] != '"' {
+		return fmt.Errorf("hash is not a JSON string: %w", BadHash)
+	}
+	return h.UnmarshalText(b[1 : len(b)-1])
+}
+
+func (h DoubleHash) MarshalText() ([]byte, error) {
+	return []byte(h.String()), nil
+}
+
+func (h *DoubleHash) UnmarshalText(b []byte) error {
+	parsed, err := NewDoubleHashFromStr(string(b))
+	if err != nil {
+		return err
+	}
+	*h = parsed
+	return nil
+}
+
+// String returns byte reversed hex form of hash
 func (h DoubleHash) String() string {
+	var r DoubleHash
+	for i := range h {
+		r[len(r)-1-i] = h[i]
+	}
+	return hex.EncodeToString(r[:])
+}
+
+// InternalString returns hex form of hash in internal byte order
+func (h DoubleHash) InternalString() string {
 	return hex.EncodeToString(h[:])
 }
 
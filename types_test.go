@@ -0,0 +1,65 @@
+//
+// types_test.go
+// Copyright (C) 2017 weirdgiraffe <giraffe@cyberzoo.xyz>
+//
+// Distributed under terms of the MIT license.
+//
+
+package bitcoin
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestDoubleHashString(t *testing.T) {
+	genesis := "000000000019d6689c085ae165831e934ff763ae46a2a6c172b3f1b60a8ce26f"
+	h, err := NewDoubleHashFromStr(genesis)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h[0] != 0x6f || h[31] != 0x00 {
+		t.Errorf("hash is not byte reversed: %x", h[:])
+	}
+	if h.String() != genesis {
+		t.Errorf("expected %s, got %s", genesis, h.String())
+	}
+	internal := "6fe28c0ab6f1b372c1a6a246ae63f74f931e8365e15a089c68d6190000000000"
+	if h.InternalString() != internal {
+		t.Errorf("expected internal %s, got %s", internal, h.InternalString())
+	}
+	b, err := json.Marshal(map[DoubleHash]DoubleHash{h: h})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"` + genesis + `":"` + genesis + `"}`
+	if string(b) != expected {
+		t.Errorf("expected JSON %s, got %s", expected, b)
+	}
+	var m map[DoubleHash]DoubleHash
+	if err = json.Unmarshal(b, &m); err != nil {
+		t.Fatal(err)
+	}
+	if m[h] != h {
+		t.Errorf("JSON round trip mismatch: %v", m)
+	}
+}
+
+func TestDoubleHashParseErrors(t *testing.T) {
+	tt := []string{
+		"",
+		"000000000019d6689c085ae165831e934ff763ae46a2a6c172b3f1b60a8ce26",
+		"000000000019d6689c085ae165831e934ff763ae46a2a6c172b3f1b60a8ce26f00",
+		"000000000019d6689c085ae165831e934ff763ae46a2a6c172b3f1b60a8ce2zz",
+	}
+	for i := range tt {
+		if _, err := NewDoubleHashFromStr(tt[i]); !errors.Is(err, BadHash) {
+			t.Errorf("case #%d: expected BadHash, got %v", i, err)
+		}
+	}
+	var h DoubleHash
+	if err := json.Unmarshal([]byte("1"), &h); !errors.Is(err, BadHash) {
+		t.Errorf("expected BadHash for non string JSON, got %v", err)
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"encoding/binary"
 	"fmt"
 	"io"
+	"math"
 )
 
 type Varint uint64
@@ -18,11 +19,10 @@ type Varint uint64
 func (v Varint) OutSize() int {
 	switch {
 	case v < 0xfd:
-
 		return 1
-	case v < 0xffff:
+	case v <= 0xffff:
 		return 3
-	case v < 0xffffffff:
+	case v <= 0xffffffff:
 		return 5
 	default:
 		return 9
@@ -37,20 +37,32 @@ func WriteVarint(w io.Writer, u Varint) (err error) {
 		if err != nil {
 			return
 		}
-	case u < 0xffff:
+	case u <= 0xffff:
 		v := uint16(u)
+		_, err = w.Write([]byte{0xfd})
+		if err != nil {
+			return
+		}
 		err = binary.Write(w, binary.LittleEndian, &v)
 		if err != nil {
 			return
 		}
-	case u < 0xffffffff:
+	case u <= 0xffffffff:
 		v := uint32(u)
+		_, err = w.Write([]byte{0xfe})
+		if err != nil {
+			return
+		}
 		err = binary.Write(w, binary.LittleEndian, &v)
 		if err != nil {
 			return
 		}
 	default:
 		v := uint64(u)
+		_, err = w.Write([]byte{0xff})
+		if err != nil {
+			return
+		}
 		err = binary.Write(w, binary.LittleEndian, &v)
 		if err != nil {
 			return
@@ -93,22 +105,79 @@ func ReadVarint(r io.Reader, u *Varint) (err error) {
 	return nil
 }
 
+// ScriptInt is a number used by script arithmetic operations. It is
+// serialized as little endian sign-magnitude number of minimal length,
+// the same as CScriptNum of bitcoin core: the highest bit of the last
+// byte is the sign and zero is an empty slice
+// check https://github.com/bitcoin/bitcoin/blob/master/src/script/script.h
 type ScriptInt struct {
 	val int64
 }
 
+const (
+	// DefaultScriptIntSize is the maximum size of arithmetic operands.
+	// Results of operations may be longer, but could not be used as
+	// operands then
+	DefaultScriptIntSize = 4
+	// LockTimeScriptIntSize is the maximum size of OP_CHECKLOCKTIMEVERIFY
+	// and OP_CHECKSEQUENCEVERIFY operands
+	LockTimeScriptIntSize = 5
+)
+
+// NewScriptInt creates ScriptInt with value v
+func NewScriptInt(v int64) ScriptInt {
+	return ScriptInt{val: v}
+}
+
+// ParseScriptInt decodes number from b, which must be at most maxSize
+// bytes long. When requireMinimal is set the number must be encoded
+// without excess zero bytes, which is required by MINIMALDATA rule
+func ParseScriptInt(b []byte, maxSize int, requireMinimal bool) (ScriptInt, error) {
+	if len(b) > maxSize {
+		return ScriptInt{}, scriptError(ErrScriptNum, "Script number overflow: %d bytes is longer than %d", len(b), maxSize)
+	}
+	if requireMinimal && !IsMinimalScriptInt(b) {
+		return ScriptInt{}, scriptError(ErrScriptNum, "Non-minimally encoded script number 0x%s", byte2hex(b))
+	}
+	return *ScriptIntFromSlice(b), nil
+}
+
+// IsMinimalScriptInt return true if b is the shortest encoding of the
+// number it represents
+func IsMinimalScriptInt(b []byte) bool {
+	if len(b) == 0 {
+		return true
+	}
+	// the last byte could be zero only if it is needed for the sign
+	// bit, which means the most significant bit of the previous byte
+	// is set. Negative zero is not minimal as well
+	if b[len(b)-1]&0x7f == 0 {
+		if len(b) == 1 || b[len(b)-2]&0x80 == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// ScriptIntFromSlice decodes number from b without size limits and
+// minimal encoding checks. It panics if b is longer than 8 bytes
 func ScriptIntFromSlice(b []byte) *ScriptInt {
 	if len(b) > 8 {
 		panic(fmt.Errorf("ScriptInt is bigger than int64"))
 	}
 	s := &ScriptInt{}
-	var offt uint = 0
+	if len(b) == 0 {
+		return s
+	}
+	var u uint64
 	for i := range b {
-		s.val |= (int64(b[i]) << offt)
-		offt += 8
+		u |= uint64(b[i]) << (8 * uint(i))
 	}
-	if ((int64(0x80)<<offt)&s.val) > 0 && (s.val > 0) {
-		s.val = -s.val
+	signBit := uint64(0x80) << (8 * uint(len(b)-1))
+	if u&signBit != 0 {
+		s.val = -int64(u &^ signBit)
+	} else {
+		s.val = int64(u)
 	}
 	return s
 }
@@ -117,22 +186,42 @@ func (s ScriptInt) Int64() int64 {
 	return s.val
 }
 
+// Int return value clamped to int32 range, which is how bitcoin core
+// converts script numbers to integers
 func (s ScriptInt) Int() int {
+	switch {
+	case s.val > math.MaxInt32:
+		return math.MaxInt32
+	case s.val < math.MinInt32:
+		return math.MinInt32
+	}
 	return int(s.val)
 }
 
+// Bytes return minimal serialization of the number
 func (s ScriptInt) Bytes() []byte {
-	b := make([]byte, 8)
-	var offt uint = 0
-	for i := range b {
-		b[i] = byte((s.val >> offt) & 0xff)
-		offt += 8
-		if b[i] == 0 {
-			if i == 0 {
-				return []byte{0}
-			}
-			return b[:i]
-		}
+	if s.val == 0 {
+		return []byte{}
+	}
+	neg := s.val < 0
+	u := uint64(s.val)
+	if neg {
+		u = ^u + 1
+	}
+	var b []byte
+	for u > 0 {
+		b = append(b, byte(u&0xff))
+		u >>= 8
+	}
+	// the most significant bit is reserved for the sign, so an extra
+	// byte is added if it is occupied by the value
+	switch {
+	case b[len(b)-1]&0x80 != 0 && neg:
+		b = append(b, 0x80)
+	case b[len(b)-1]&0x80 != 0:
+		b = append(b, 0x00)
+	case neg:
+		b[len(b)-1] |= 0x80
 	}
 	return b
 }
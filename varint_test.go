@@ -0,0 +1,142 @@
+//
+// varint_test.go
+// Copyright (C) 2017 weirdgiraffe <giraffe@cyberzoo.xyz>
+//
+// Distributed under terms of the MIT license.
+//
+
+package bitcoin
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestScriptIntBytes(t *testing.T) {
+	tt := []struct {
+		val int64
+		hex string
+	}{
+		{0, ""},
+		{1, "01"},
+		{-1, "81"},
+		{16, "10"},
+		{127, "7f"},
+		{-127, "ff"},
+		{128, "8000"},
+		{-128, "8080"},
+		{255, "ff00"},
+		{-255, "ff80"},
+		{256, "0001"},
+		{-256, "0081"},
+		{0x10000, "000001"},
+		{0x7fffffff, "ffffff7f"},
+		{-0x7fffffff, "ffffffff"},
+		{0x80000000, "0000008000"},
+		{-0x80000000, "0000008080"},
+		{0xffffffff, "ffffffff00"},
+		{math.MaxInt64, "ffffffffffffff7f"},
+		{-math.MaxInt64, "ffffffffffffffff"},
+	}
+	for i := range tt {
+		b := NewScriptInt(tt[i].val).Bytes()
+		if !bytes.Equal(b, hex2byte(tt[i].hex)) {
+			t.Errorf("case #%d expected %s, got %x", i+1, tt[i].hex, b)
+		}
+		v := ScriptIntFromSlice(hex2byte(tt[i].hex)).Int64()
+		if v != tt[i].val {
+			t.Errorf("case #%d expected %d, got %d", i+1, tt[i].val, v)
+		}
+	}
+}
+
+func TestScriptIntRoundTrip(t *testing.T) {
+	check := func(v int64) {
+		b := NewScriptInt(v).Bytes()
+		if !IsMinimalScriptInt(b) {
+			t.Fatalf("%d encoded non-minimally as %x", v, b)
+		}
+		n, err := ParseScriptInt(b, 8, true)
+		if err != nil {
+			t.Fatalf("%d encoded as %x: %v", v, b, err)
+		}
+		if n.Int64() != v {
+			t.Fatalf("%d encoded as %x decoded as %d", v, b, n.Int64())
+		}
+	}
+	for v := int64(-0x20000); v <= 0x20000; v++ {
+		check(v)
+	}
+	for k := uint(0); k < 63; k++ {
+		for _, v := range []int64{1 << k, 1<<k - 1, 1<<k + 1} {
+			check(v)
+			check(-v)
+		}
+	}
+	check(math.MaxInt64)
+	check(-math.MaxInt64)
+
+	// every encoding of up to 2 bytes decodes to a number, which is
+	// encoded back to the same bytes only if the encoding is minimal
+	for u := 0; u < 0x10000+0x100+1; u++ {
+		var b []byte
+		switch {
+		case u == 0:
+		case u <= 0x100:
+			b = []byte{byte(u - 1)}
+		default:
+			b = []byte{byte(u - 0x101), byte((u - 0x101) >> 8)}
+		}
+		e := NewScriptInt(ScriptIntFromSlice(b).Int64()).Bytes()
+		if bytes.Equal(b, e) != IsMinimalScriptInt(b) {
+			t.Fatalf("%x encoded back as %x, minimal: %v", b, e, IsMinimalScriptInt(b))
+		}
+	}
+}
+
+func TestParseScriptInt(t *testing.T) {
+	tt := []struct {
+		hex        string
+		maxSize    int
+		minimal    bool
+		val        int64
+		expect_err bool
+	}{
+		{"", DefaultScriptIntSize, true, 0, false},
+		{"00", DefaultScriptIntSize, false, 0, false},
+		{"00", DefaultScriptIntSize, true, 0, true},
+		{"80", DefaultScriptIntSize, false, 0, false},
+		{"80", DefaultScriptIntSize, true, 0, true},
+		{"0100", DefaultScriptIntSize, false, 1, false},
+		{"0100", DefaultScriptIntSize, true, 0, true},
+		{"0180", DefaultScriptIntSize, false, -1, false},
+		{"0180", DefaultScriptIntSize, true, 0, true},
+		{"8000", DefaultScriptIntSize, true, 128, false},
+		{"8080", DefaultScriptIntSize, true, -128, false},
+		{"ffffffff", DefaultScriptIntSize, true, -0x7fffffff, false},
+		{"0000008000", DefaultScriptIntSize, false, 0, true},
+		{"0000008000", LockTimeScriptIntSize, true, 0x80000000, false},
+		{"ffffffff00", LockTimeScriptIntSize, true, 0xffffffff, false},
+		{"000000000000", LockTimeScriptIntSize, false, 0, true},
+	}
+	for i := range tt {
+		n, err := ParseScriptInt(hex2byte(tt[i].hex), tt[i].maxSize, tt[i].minimal)
+		if err != nil {
+			if !tt[i].expect_err {
+				t.Errorf("case #%d error: %v", i+1, err)
+			} else if !errors.Is(err, ErrScriptNum) {
+				t.Errorf("case #%d expected %s, got %v", i+1, ErrScriptNum, err)
+			}
+			continue
+		}
+		if tt[i].expect_err {
+			t.Errorf("case #%d expected to fail", i+1)
+			continue
+		}
+		if n.Int64() != tt[i].val {
+			t.Errorf("case #%d expected %d, got %d", i+1, tt[i].val, n.Int64())
+		}
+	}
+}
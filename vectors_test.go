@@ -0,0 +1,374 @@
+//
+// vectors_test.go
+// Copyright (C) 2017 weirdgiraffe <giraffe@cyberzoo.xyz>
+//
+// Distributed under terms of the MIT license.
+//
+
+package bitcoin
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// test vectors are taken from bitcoin core src/test/data
+
+var testFlagNames = map[string]ScriptFlags{
+	"NONE":                                  ScriptVerifyNone,
+	"P2SH":                                  ScriptVerifyP2SH,
+	"STRICTENC":                             ScriptVerifyStrictEncoding,
+	"DERSIG":                                ScriptVerifyDERSignatures,
+	"LOW_S":                                 ScriptVerifyLowS,
+	"NULLDUMMY":                             ScriptVerifyNullDummy,
+	"SIGPUSHONLY":                           ScriptVerifySigPushOnly,
+	"MINIMALDATA":                           ScriptVerifyMinimalData,
+	"DISCOURAGE_UPGRADABLE_NOPS":            ScriptVerifyDiscourageUpgradableNops,
+	"CLEANSTACK":                            ScriptVerifyCleanStack,
+	"CHECKLOCKTIMEVERIFY":                   ScriptVerifyCheckLockTimeVerify,
+	"CHECKSEQUENCEVERIFY":                   ScriptVerifyCheckSequenceVerify,
+	"WITNESS":                               ScriptVerifyWitness,
+	"DISCOURAGE_UPGRADABLE_WITNESS_PROGRAM": ScriptVerifyDiscourageUpgradableWitnessProgram,
+	"MINIMALIF":                             ScriptVerifyMinimalIf,
+	"NULLFAIL":                              ScriptVerifyNullFail,
+	"WITNESS_PUBKEYTYPE":                    ScriptVerifyWitnessPubKeyType,
+	"CONST_SCRIPTCODE":                      ScriptVerifyConstScriptCode,
+	"TAPROOT":                               ScriptVerifyTaproot,
+	"DISCOURAGE_UPGRADABLE_TAPROOT_VERSION": ScriptVerifyDiscourageUpgradableTaprootVersion,
+	"DISCOURAGE_OP_SUCCESS":                 ScriptVerifyDiscourageOpSuccess,
+	"DISCOURAGE_UPGRADABLE_PUBKEYTYPE":      ScriptVerifyDiscourageUpgradablePubKeyType,
+}
+
+// testAllFlags is the set of all known flags. Flags of tx_valid.json
+// vectors are the ones to exclude from it
+var testAllFlags = func() ScriptFlags {
+	var flags ScriptFlags
+	for _, f := range testFlagNames {
+		flags |= f
+	}
+	return flags
+}()
+
+// testTrimFlags and testFillFlags are the same as TrimFlags and FillFlags
+// of bitcoin core: they make the flags a valid combination, where
+// ScriptVerifyWitness requires ScriptVerifyP2SH and
+// ScriptVerifyCleanStack requires ScriptVerifyWitness
+func testTrimFlags(flags ScriptFlags) ScriptFlags {
+	if flags&ScriptVerifyP2SH == 0 {
+		flags &^= ScriptVerifyWitness
+	}
+	if flags&ScriptVerifyWitness == 0 {
+		flags &^= ScriptVerifyCleanStack
+	}
+	return flags
+}
+
+func testFillFlags(flags ScriptFlags) ScriptFlags {
+	if flags&ScriptVerifyCleanStack != 0 {
+		flags |= ScriptVerifyWitness
+	}
+	if flags&ScriptVerifyWitness != 0 {
+		flags |= ScriptVerifyP2SH
+	}
+	return flags
+}
+
+// testExcludeEachFlag returns valid combinations of flags with one of
+// the flags removed
+func testExcludeEachFlag(flags ScriptFlags) []ScriptFlags {
+	var ret []ScriptFlags
+	seen := make(map[ScriptFlags]bool)
+	for _, f := range testFlagNames {
+		excluded := testTrimFlags(flags &^ f)
+		if excluded != flags && !seen[excluded] {
+			seen[excluded] = true
+			ret = append(ret, excluded)
+		}
+	}
+	return ret
+}
+
+func parseTestFlags(s string) (ScriptFlags, error) {
+	flags := ScriptVerifyNone
+	if s == "" || s == "NONE" {
+		return flags, nil
+	}
+	for _, name := range strings.Split(s, ",") {
+		f, ok := testFlagNames[name]
+		if !ok {
+			return 0, fmt.Errorf("unknown flag %s", name)
+		}
+		flags |= f
+	}
+	return flags, nil
+}
+
+func loadTestVectors(t *testing.T, name string) [][]interface{} {
+	b, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", name, err)
+	}
+	var v [][]interface{}
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	err = d.Decode(&v)
+	if err != nil {
+		t.Fatalf("failed to parse %s: %v", name, err)
+	}
+	return v
+}
+
+// testCreditingTx and testSpendingTx are the same as
+// BuildCreditingTransaction and BuildSpendingTransaction of bitcoin core
+func testCreditingTx(scriptPubKey []byte, amount uint64) *Tx {
+	tx := &Tx{
+		Version: 1,
+		In: []TxIn{{
+			PrevTxOutIndx: 0xffffffff,
+			Script:        []byte{OP_0, OP_0},
+			SequenceNum:   SequenceFinal,
+		}},
+		Out: []TxOut{{Value: amount, Script: scriptPubKey}},
+	}
+	tx.Hash.Update(tx.Raw())
+	return tx
+}
+
+func testSpendingTx(scriptSig []byte, witness [][]byte, credit *Tx) *Tx {
+	tx := &Tx{
+		Version: 1,
+		In: []TxIn{{
+			PrevTx:      credit.Hash,
+			Script:      scriptSig,
+			SequenceNum: SequenceFinal,
+			Witness:     witness,
+		}},
+		Out: []TxOut{{Value: credit.Out[0].Value}},
+	}
+	tx.Hash.Update(tx.Raw())
+	return tx
+}
+
+func testAmount(n json.Number) (uint64, error) {
+	f, err := n.Float64()
+	if err != nil {
+		return 0, err
+	}
+	return uint64(math.Round(f * 1e8)), nil
+}
+
+// testInternalKey is x-only public key of private key 1, which bitcoin
+// core uses as the taproot internal key of script_tests.json
+var testInternalKey = hex2byte("79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798")
+
+// testControlBlock returns control block of tapscript, which is the only
+// leaf of the taproot tree, and the taproot output key it commits to
+func testControlBlock(t *testing.T, script []byte) ([]byte, []byte) {
+	leafHash := TapLeafHash(TapLeafTapscript, script)
+	key, odd, err := TaprootOutputKey(testInternalKey, leafHash[:])
+	if err != nil {
+		t.Fatalf("failed to compute taproot output key: %v", err)
+	}
+	control := []byte{TapLeafTapscript}
+	if odd {
+		control[0] |= 1
+	}
+	return append(control, testInternalKey...), key
+}
+
+func TestScriptVectors(t *testing.T) {
+	for i, v := range loadTestVectors(t, "script_tests.json") {
+		if len(v) == 1 {
+			continue
+		}
+		var witness [][]byte
+		var amount uint64
+		var outputKey []byte
+		if w, ok := v[0].([]interface{}); ok {
+			var err error
+			amount, err = testAmount(w[len(w)-1].(json.Number))
+			if err != nil {
+				t.Fatalf("line #%d bad amount: %v", i+1, err)
+			}
+			for _, item := range w[:len(w)-1] {
+				item := item.(string)
+				switch {
+				case strings.HasPrefix(item, "#SCRIPT#"):
+					script, err := Assemble(strings.TrimPrefix(item, "#SCRIPT#"))
+					if err != nil {
+						t.Fatalf("line #%d witness script: %v", i+1, err)
+					}
+					witness = append(witness, script)
+				case item == "#CONTROLBLOCK#":
+					var control []byte
+					control, outputKey = testControlBlock(t, witness[len(witness)-1])
+					witness = append(witness, control)
+				default:
+					witness = append(witness, hex2byte(item))
+				}
+			}
+			v = v[1:]
+		}
+		scriptSig, err := Assemble(v[0].(string))
+		if err != nil {
+			t.Fatalf("line #%d scriptSig: %v", i+1, err)
+		}
+		var scriptPubKey []byte
+		if v[1].(string) == "0x51 0x20 #TAPROOTOUTPUT#" {
+			scriptPubKey = append([]byte{OP_1, 0x20}, outputKey...)
+		} else {
+			scriptPubKey, err = Assemble(v[1].(string))
+			if err != nil {
+				t.Fatalf("line #%d scriptPubKey: %v", i+1, err)
+			}
+		}
+		flags, err := parseTestFlags(v[2].(string))
+		if err != nil {
+			t.Fatalf("line #%d: %v", i+1, err)
+		}
+		expected := v[3].(string)
+
+		credit := testCreditingTx(scriptPubKey, amount)
+		spend := testSpendingTx(scriptSig, witness, credit)
+		prevOuts := PrevOutMap{OutPoint{credit.Hash, 0}: &credit.Out[0]}
+		err = VerifyInput(spend, 0, flags, prevOuts)
+		got := ErrOK
+		if err != nil {
+			got = ErrUnknown
+			if se, ok := err.(*ScriptError); ok {
+				got = se.Code
+			}
+		}
+		if got.String() != expected {
+			t.Errorf("line #%d expected %s, got %v\n%v", i+1, expected, err, v)
+		}
+	}
+}
+
+// testTx parses spent outputs and serialized transaction of transaction
+// test vector
+func testTx(t *testing.T, v []interface{}) (*Tx, PrevOutMap, error) {
+	prevOuts := make(PrevOutMap)
+	for _, p := range v[0].([]interface{}) {
+		p := p.([]interface{})
+		indx, err := strconv.ParseInt(string(p[1].(json.Number)), 10, 64)
+		if err != nil {
+			return nil, nil, err
+		}
+		script, err := Assemble(p[2].(string))
+		if err != nil {
+			return nil, nil, err
+		}
+		out := &TxOut{Script: script}
+		if len(p) > 3 {
+			out.Value, err = strconv.ParseUint(string(p[3].(json.Number)), 10, 64)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+		prevOuts[OutPoint{mustParseHash(p[0].(string)), uint32(indx)}] = out
+	}
+	tx, err := ReadTx(bytes.NewReader(hex2byte(v[1].(string))))
+	if err != nil {
+		return nil, nil, err
+	}
+	return tx, prevOuts, nil
+}
+
+// tx_valid.json vectors list the flags to exclude from testAllFlags,
+// tx_invalid.json vectors list the flags to verify with, or BADTX when
+// CheckTransaction must fail
+func TestTxValidVectors(t *testing.T) {
+	for i, v := range loadTestVectors(t, "tx_valid.json") {
+		if _, ok := v[0].(string); ok {
+			continue
+		}
+		tx, prevOuts, err := testTx(t, v)
+		if err != nil {
+			t.Fatalf("line #%d: %v", i+1, err)
+		}
+		excluded, err := parseTestFlags(v[2].(string))
+		if err != nil {
+			t.Fatalf("line #%d: %v", i+1, err)
+		}
+		if err = CheckTransaction(tx); err != nil {
+			t.Errorf("line #%d check error: %v\n%v", i+1, err, v)
+		}
+		flags := testAllFlags &^ excluded
+		if flags != testFillFlags(flags) {
+			t.Errorf("line #%d bad flags\n%v", i+1, v)
+		}
+		for j := range tx.In {
+			err = VerifyInput(tx, j, flags, prevOuts)
+			if err != nil {
+				t.Errorf("line #%d input %d error: %v\n%v", i+1, j, err, v)
+			}
+		}
+		if err = VerifyTx(tx, flags, prevOuts); err != nil {
+			t.Errorf("line #%d error: %v\n%v", i+1, err, v)
+		}
+		for name, f := range testFlagNames {
+			err = VerifyTx(tx, testTrimFlags(flags&^f), prevOuts)
+			if err != nil {
+				t.Errorf("line #%d error with %s unset: %v\n%v", i+1, name, err, v)
+			}
+		}
+		for _, f := range testExcludeEachFlag(excluded) {
+			if VerifyTx(tx, testAllFlags&^f, prevOuts) == nil {
+				t.Errorf("line #%d too many flags excluded\n%v", i+1, v)
+			}
+		}
+	}
+}
+
+func TestTxInvalidVectors(t *testing.T) {
+	for i, v := range loadTestVectors(t, "tx_invalid.json") {
+		if _, ok := v[0].(string); ok {
+			continue
+		}
+		tx, prevOuts, err := testTx(t, v)
+		if err != nil {
+			t.Errorf("line #%d: %v\n%v", i+1, err, v)
+			continue
+		}
+		if err = CheckTransaction(tx); err != nil {
+			if v[2].(string) != "BADTX" {
+				t.Errorf("line #%d unexpected check error: %v\n%v", i+1, err, v)
+			}
+			continue
+		}
+		flags, err := parseTestFlags(v[2].(string))
+		if err != nil {
+			t.Fatalf("line #%d: %v", i+1, err)
+		}
+		if flags != testFillFlags(flags) {
+			t.Errorf("line #%d bad flags\n%v", i+1, v)
+		}
+		valid := true
+		for j := 0; valid && j < len(tx.In); j++ {
+			valid = VerifyInput(tx, j, flags, prevOuts) == nil
+		}
+		if valid {
+			t.Errorf("line #%d expected to fail\n%v", i+1, v)
+		}
+		if VerifyTx(tx, flags, prevOuts) == nil {
+			t.Errorf("line #%d expected VerifyTx to fail\n%v", i+1, v)
+		}
+		for name, f := range testFlagNames {
+			if VerifyTx(tx, testFillFlags(flags|f), prevOuts) == nil {
+				t.Errorf("line #%d expected to fail with %s set\n%v", i+1, name, v)
+			}
+		}
+		for _, f := range testExcludeEachFlag(flags) {
+			if err = VerifyTx(tx, f, prevOuts); err != nil {
+				t.Errorf("line #%d too many flags: %v\n%v", i+1, err, v)
+			}
+		}
+	}
+}
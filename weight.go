@@ -0,0 +1,88 @@
+//
+// weight.go
+// Copyright (C) 2017 weirdgiraffe <giraffe@cyberzoo.xyz>
+//
+// Distributed under terms of the MIT license.
+//
+
+package bitcoin
+
+// WitnessScaleFactor is the weight of non-witness byte (BIP141)
+const WitnessScaleFactor = 4
+
+// Size return serialized size of input without witness
+func (in *TxIn) Size() int {
+	return len(in.PrevTx) + 4 + Varint(len(in.Script)).OutSize() + len(in.Script) + 4
+}
+
+// WitnessSize return serialized size of input witness stack. It is
+// counted for all inputs of witness serialized transaction, even for
+// inputs with empty witness
+func (in *TxIn) WitnessSize() int {
+	return witnessSize(in.Witness)
+}
+
+// Size return serialized size of output
+func (out *TxOut) Size() int {
+	return 8 + Varint(len(out.Script)).OutSize() + len(out.Script)
+}
+
+// BaseSize return size of transaction serialization without witness,
+// i.e. len(tx.Raw())
+func (tx *Tx) BaseSize() int {
+	n := 4 + Varint(len(tx.In)).OutSize() + Varint(len(tx.Out)).OutSize() + 4
+	for i := range tx.In {
+		n += tx.In[i].Size()
+	}
+	for i := range tx.Out {
+		n += tx.Out[i].Size()
+	}
+	return n
+}
+
+// TotalSize return size of transaction serialization with witness,
+// i.e. len(tx.RawWitness())
+func (tx *Tx) TotalSize() int {
+	n := tx.BaseSize()
+	if tx.HasWitness() {
+		// marker and flag bytes
+		n += 2
+		for i := range tx.In {
+			n += tx.In[i].WitnessSize()
+		}
+	}
+	return n
+}
+
+// Weight return transaction weight in weight units (BIP141)
+func (tx *Tx) Weight() int {
+	return tx.BaseSize()*(WitnessScaleFactor-1) + tx.TotalSize()
+}
+
+// VirtualSize return transaction weight divided by 4 and rounded up
+func (tx *Tx) VirtualSize() int {
+	return (tx.Weight() + WitnessScaleFactor - 1) / WitnessScaleFactor
+}
+
+// Size return serialized size of block with witness data
+func (b *Block) Size() int {
+	n := BlockHeaderSize + Varint(len(b.tx)).OutSize()
+	for i := range b.tx {
+		n += b.tx[i].TotalSize()
+	}
+	return n
+}
+
+// StrippedSize return serialized size of block without witness data
+func (b *Block) StrippedSize() int {
+	n := BlockHeaderSize + Varint(len(b.tx)).OutSize()
+	for i := range b.tx {
+		n += b.tx[i].BaseSize()
+	}
+	return n
+}
+
+// Weight return block weight in weight units (BIP141)
+func (b *Block) Weight() int {
+	return b.StrippedSize()*(WitnessScaleFactor-1) + b.Size()
+}
@@ -0,0 +1,62 @@
+//
+// weight_test.go
+// Copyright (C) 2017 weirdgiraffe <giraffe@cyberzoo.xyz>
+//
+// Distributed under terms of the MIT license.
+//
+
+package bitcoin
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestTxWeight(t *testing.T) {
+	tx := hex2tx(t, testP2WPKHTx)
+	if tx.In[0].Size() != 41 || tx.In[0].WitnessSize() != 106 || tx.Out[0].Size() != 31 {
+		t.Errorf("unexpected input/output sizes %d %d %d",
+			tx.In[0].Size(), tx.In[0].WitnessSize(), tx.Out[0].Size())
+	}
+	if tx.BaseSize() != 82 || tx.BaseSize() != len(tx.Raw()) {
+		t.Errorf("unexpected base size %d", tx.BaseSize())
+	}
+	if tx.TotalSize() != 190 || tx.TotalSize() != len(tx.RawWitness()) {
+		t.Errorf("unexpected total size %d", tx.TotalSize())
+	}
+	if tx.Weight() != 436 {
+		t.Errorf("unexpected weight %d", tx.Weight())
+	}
+	if tx.VirtualSize() != 109 {
+		t.Errorf("unexpected virtual size %d", tx.VirtualSize())
+	}
+
+	stripped, err := ReadTx(bytes.NewBuffer(tx.Raw()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stripped.TotalSize() != 82 || stripped.Weight() != 82*4 || stripped.VirtualSize() != 82 {
+		t.Errorf("unexpected stripped tx sizes %d %d %d",
+			stripped.TotalSize(), stripped.Weight(), stripped.VirtualSize())
+	}
+}
+
+func TestBlockWeight(t *testing.T) {
+	bf, err := OpenBlockFile("assets/testblock.dat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bf.Close()
+	b, err := bf.Block(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// size from the block file record, block has no witness transactions
+	size := 0x0f17f6
+	if b.Size() != size || b.StrippedSize() != size {
+		t.Errorf("expected size %d, got %d and stripped %d", size, b.Size(), b.StrippedSize())
+	}
+	if b.Weight() != size*WitnessScaleFactor {
+		t.Errorf("unexpected weight %d", b.Weight())
+	}
+}
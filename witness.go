@@ -0,0 +1,120 @@
+//
+// witness.go
+// Copyright (C) 2017 weirdgiraffe <giraffe@cyberzoo.xyz>
+//
+// Distributed under terms of the MIT license.
+//
+
+package bitcoin
+
+import (
+	"bytes"
+	"crypto/sha256"
+)
+
+// verifyWitnessProgram verifies witness of the current input against
+// witness program of spent output
+// check https://github.com/bitcoin/bips/blob/master/bip-0141.mediawiki
+func (e *Engine) verifyWitnessProgram(witness [][]byte, version int, program []byte, p2sh bool) error {
+	switch {
+	case version == 0 && len(program) == 32:
+		// pay-to-witness-script-hash
+		if len(witness) == 0 {
+			return scriptError(ErrWitnessProgramWitnessEmpty, "Witness program witness is empty")
+		}
+		script := witness[len(witness)-1]
+		h := sha256.Sum256(script)
+		if !bytes.Equal(h[:], program) {
+			return scriptError(ErrWitnessProgramMismatch, "Witness program hash mismatch")
+		}
+		return e.executeWitnessScript(witness[:len(witness)-1], script, sigVersionWitnessV0)
+	case version == 0 && len(program) == 20:
+		// pay-to-witness-public-key-hash
+		if len(witness) != 2 {
+			return scriptError(ErrWitnessProgramMismatch, "Witness program hash mismatch")
+		}
+		script := make([]byte, 0, 25)
+		script = append(script, OP_DUP, OP_HASH160, 0x14)
+		script = append(script, program...)
+		script = append(script, OP_EQUALVERIFY, OP_CHECKSIG)
+		return e.executeWitnessScript(witness, script, sigVersionWitnessV0)
+	case version == 0:
+		return scriptError(ErrWitnessProgramWrongLength, "Witness program has wrong length %d", len(program))
+	case version == 1 && len(program) == 32 && !p2sh:
+		// without taproot rules the output is anyone-can-spend
+		if e.flags&ScriptVerifyTaproot == 0 {
+			return nil
+		}
+		return e.verifyTaproot(witness, program)
+	}
+	// other versions are reserved for future soft forks
+	if e.flags&ScriptVerifyDiscourageUpgradableWitnessProgram != 0 {
+		return scriptError(ErrDiscourageUpgradableWitnessProgram, "Witness program version %d is unknown", version)
+	}
+	return nil
+}
+
+// executeWitnessScript executes script on a stack initialized with
+// witness items. Script must leave exactly one true item on the stack
+func (e *Engine) executeWitnessScript(witness [][]byte, script []byte, sv sigVersion) error {
+	if sv == sigVersionWitnessV0 {
+		err := e.fetchAmount()
+		if err != nil {
+			return err
+		}
+	}
+	if sv == sigVersionTapscript {
+		// OP_SUCCESSx anywhere in the script makes it valid
+		success, err := hasOpSuccess(script)
+		if err != nil {
+			return err
+		}
+		if success {
+			if e.flags&ScriptVerifyDiscourageOpSuccess != 0 {
+				return scriptError(ErrDiscourageOpSuccess, "OP_SUCCESSx is not allowed")
+			}
+			return nil
+		}
+		// other witness scripts check stack size during execution
+		if len(witness) > maxStackSize {
+			return scriptError(ErrStackSize, "Witness has more than %d items", maxStackSize)
+		}
+	}
+	e.main.Reset()
+	for i := range witness {
+		if len(witness[i]) > maxScriptElementSize {
+			return scriptError(ErrPushSize, "Witness item is bigger than %d bytes", maxScriptElementSize)
+		}
+		e.main.PushSlice(witness[i])
+	}
+	e.sigVersion = sv
+	err := e.Execute(script)
+	if err != nil {
+		return err
+	}
+	if e.main.Len() != 1 {
+		return scriptError(ErrCleanStack, "Witness script must leave exactly one item on the stack")
+	}
+	if !e.topIsTrue() {
+		return scriptError(ErrEvalFalse, "Script evaluated without error but finished with a false/empty top stack element")
+	}
+	return nil
+}
+
+// fetchAmount looks up amount of the output spent by the current input
+func (e *Engine) fetchAmount() error {
+	if e.spentOuts != nil {
+		e.amount = e.spentOuts[e.inIndx].Value
+		return nil
+	}
+	if e.prevOuts == nil {
+		return noPrevOutFetcher()
+	}
+	in := &e.tx.In[e.inIndx]
+	out, err := e.prevOuts.FetchPrevOut(in.PrevTx, in.PrevTxOutIndx)
+	if err != nil {
+		return err
+	}
+	e.amount = out.Value
+	return nil
+}